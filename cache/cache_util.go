@@ -6,33 +6,37 @@ import (
 	"sync"
 	"time"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
+// Maximum number of batches of a Get call prefetched in parallel. The batch queries run within the Get
+// hydrate, so besides waiting for its rate limiter they are bounded here.
+const maxConcurrentBatches = 5
+
+// CacheUtil is safe for concurrent use. The table maps are only written when it's created, the records
+// are guarded by the lock of their budget and the id sets have their own locks.
 type CacheUtil struct {
-	tableCache        map[string]*cache.Cache
+	records           *recordStore
 	tableIdSet        map[string]*Set
 	tableKeyStructMap map[string]*KeyStruct
 	IdFormatter       func(string) string
 	TableKeyStruct    []KeyStruct
 	cacheExpiration   time.Duration
-	cleanupInterval   time.Duration
 	batchSize         int
 }
 
-func generateTableCache(tableKeyStruct []KeyStruct, cacheExpiration time.Duration, cleanupInterval time.Duration) map[string]*cache.Cache {
-	tableCache := make(map[string]*cache.Cache)
-	for _, keyStruct := range tableKeyStruct {
-		tableCache[keyStruct.Name] = cache.New(cacheExpiration, cleanupInterval)
-	}
-	return tableCache
+// Limits bound the memory of a CacheUtil. MaxRecordsPerTable also bounds the ids waiting to be
+// prefetched per table. Zero values are unbounded.
+type Limits struct {
+	MaxRecordsPerTable int
+	// Budget shared with other caches, nil for an unbounded budget of the CacheUtil
+	Budget *Budget
 }
 
-func generateIdSet(tableKeyStruct []KeyStruct) map[string]*Set {
+func generateIdSet(tableKeyStruct []KeyStruct, maxSize int) map[string]*Set {
 	tableIdSet := make(map[string]*Set)
 	for _, keyStruct := range tableKeyStruct {
-		tableIdSet[keyStruct.Name] = &Set{}
+		tableIdSet[keyStruct.Name] = NewSet(maxSize)
 	}
 	return tableIdSet
 }
@@ -40,13 +44,13 @@ func generateIdSet(tableKeyStruct []KeyStruct) map[string]*Set {
 func generateTableKeyStructMap(tableKeyStruct []KeyStruct) map[string]*KeyStruct {
 	tableKeyStructMap := make(map[string]*KeyStruct)
 
-	for _, keyStruct := range tableKeyStruct {
-		tableKeyStructMap[keyStruct.Name] = &keyStruct
+	for i := range tableKeyStruct {
+		tableKeyStructMap[tableKeyStruct[i].Name] = &tableKeyStruct[i]
 	}
 	return tableKeyStructMap
 }
 
-func NewCacheUtil(tableKeyStruct []KeyStruct, cacheExpiration time.Duration, cleanupInterval time.Duration, batchSize int, idFormatter func(id string) string) *CacheUtil {
+func NewCacheUtil(tableKeyStruct []KeyStruct, cacheExpiration time.Duration, batchSize int, idFormatter func(id string) string, limits Limits) *CacheUtil {
 
 	// if batch size is not provided, set it to 50
 	if batchSize <= 0 {
@@ -59,14 +63,18 @@ func NewCacheUtil(tableKeyStruct []KeyStruct, cacheExpiration time.Duration, cle
 		}
 	}
 
+	budget := limits.Budget
+	if budget == nil {
+		budget = NewBudget(0)
+	}
+
 	return &CacheUtil{
-		tableCache:        generateTableCache(tableKeyStruct, cacheExpiration, cleanupInterval),
-		tableIdSet:        generateIdSet(tableKeyStruct),
+		records:           newRecordStore(tableKeyStruct, budget, limits.MaxRecordsPerTable, cacheExpiration),
+		tableIdSet:        generateIdSet(tableKeyStruct, limits.MaxRecordsPerTable),
 		TableKeyStruct:    tableKeyStruct,
 		tableKeyStructMap: generateTableKeyStructMap(tableKeyStruct),
 		IdFormatter:       idFormatter,
 		cacheExpiration:   cacheExpiration,
-		cleanupInterval:   cleanupInterval,
 		batchSize:         batchSize,
 	}
 }
@@ -78,13 +86,6 @@ func (c *CacheUtil) getIdSetForTableName(tableName string) *Set {
 	return nil
 }
 
-func (c *CacheUtil) getCacheForTableName(tableName string) *cache.Cache {
-	if cache, ok := c.tableCache[tableName]; ok {
-		return cache
-	}
-	return nil
-}
-
 func (c *CacheUtil) getKeyStructForTableName(tableName string) *KeyStruct {
 	if keyStruct, ok := c.tableKeyStructMap[tableName]; ok {
 		return keyStruct
@@ -92,9 +93,17 @@ func (c *CacheUtil) getKeyStructForTableName(tableName string) *KeyStruct {
 	return nil
 }
 
-// TODO create generator function for this
-// so that memory can be freed up
-// discards any entries in cache.
+// getColumnNames returns the names of the requested columns
+func getColumnNames(columnsMap map[string]*plugin.Column) []string {
+	columns := make([]string, 0, len(columnsMap))
+	for column := range columnsMap {
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// getKeysToPullInBatches returns the ids of the table to prefetch, in batches of batchSize. Ids of records that
+// are cached with all the columns are skipped, and ids waiting for longer than the cache expiration are dropped.
 func (c *CacheUtil) getKeysToPullInBatches(ctx context.Context, tableName string, batchSize int, columnsMap map[string]*plugin.Column) [][]string {
 	startTime := time.Now()
 	defer measureTime(ctx, startTime, "getKeysToPullInBatches")
@@ -103,43 +112,23 @@ func (c *CacheUtil) getKeysToPullInBatches(ctx context.Context, tableName string
 	var currentTime = time.Now()
 	var currentBatch []string
 
-	tableCache := c.getCacheForTableName(tableName)
 	idSet := c.getIdSetForTableName(tableName)
-
-	for key, time := range *idSet {
-		if tableCache != nil {
-			if record, exists := tableCache.Get(key); exists {
-				// if required element is already in cache increment TTL for the element in cache
-				tableCache.Set(key, record, c.cacheExpiration)
-				columnsFound := true
-				if recordCache, ok := record.(*cache.Cache); ok {
-					plugin.Logger(ctx).Debug("salesforce.getKeysToPullInBatches record exists in cache", recordCache.Items())
-					for column, _ := range columnsMap {
-						if value, exists := recordCache.Get(column); exists {
-							// if the required column is present in the record, increment TTL for the element in cache
-							recordCache.Set(column, value, c.cacheExpiration)
-						} else {
-							plugin.Logger(ctx).Debug("salesforce.getKeysToPullInBatches column not found in cache", column)
-							columnsFound = false
-							break
-						}
-					}
-				}
-				if columnsFound {
-
-					plugin.Logger(ctx).Debug("salesforce.getKeysToPullInBatches all columns are present in cache ", columnsMap)
-					// if all the required columns are present in the record,
-					// then we don't need to pull the record
-					continue
-				}
-			} else {
-				plugin.Logger(ctx).Debug("salesforce.getKeysToPullInBatches record does not exist in cache", key)
-			}
-		} else {
-			plugin.Logger(ctx).Debug("salesforce.getKeysToPullInBatches table cache in nil", tableName)
+	if idSet == nil {
+		return result
+	}
+	idSet.RemoveOlderThan(currentTime.Add(-c.cacheExpiration))
+	columns := getColumnNames(columnsMap)
+
+	// Iterate over a copy as list streams keep adding ids while the batches are built
+	for key, time := range idSet.Items() {
+		if _, columnsFound := c.records.lookup(tableName, key, columns); columnsFound {
+			// if all the required columns are present in the record,
+			// then we don't need to pull the record
+			idSet.Remove(key)
+			continue
 		}
 
-		currentBatch = append(currentBatch, c.IdFormatter(key))
+		currentBatch = append(currentBatch, key)
 
 		if len(currentBatch) == batchSize {
 			result = append(result, currentBatch)
@@ -156,35 +145,27 @@ func (c *CacheUtil) getKeysToPullInBatches(ctx context.Context, tableName string
 }
 
 func (c *CacheUtil) AddRecordToTableCache(ctx context.Context, tableName string, id string, updatedRecord map[string]interface{}) {
-	tableCache := c.getCacheForTableName(tableName)
-
-	if record, exists := tableCache.Get(id); exists {
-		// if required element is already in cache increment TTL for the element in cache
-		tableCache.Set(id, record, c.cacheExpiration)
-		if recordCache, ok := record.(*cache.Cache); ok {
-			for column, value := range updatedRecord {
-				recordCache.Set(column, value, c.cacheExpiration)
-			}
-		}
-	} else {
-		recordCache := cache.New(c.cacheExpiration, c.cleanupInterval)
-		for column, value := range updatedRecord {
-			recordCache.Set(column, value, c.cacheExpiration)
-		}
-		tableCache.Set(id, recordCache, c.cacheExpiration)
-	}
+	c.records.put(tableName, id, updatedRecord)
 }
 
 // The function is used along with the List call in plugin and adds the ids to the id cache of the foreign table
 // and records to the table cache
 func (c *CacheUtil) AddIdsToForeignTableCache(ctx context.Context, tableName string, record map[string]interface{}) {
 	keyStruct := c.getKeyStructForTableName(tableName)
+	// Tables without a key struct are not cached
+	if keyStruct == nil {
+		return
+	}
 	// Add foreign keys to the id set
 	for _, fk := range keyStruct.Fk {
+		idSet := c.getIdSetForTableName(fk.ForeignTableName)
+		if idSet == nil {
+			continue
+		}
 		id, exists := record[fk.Key]
 		if exists {
 			if idValue, ok := id.(string); ok {
-				c.getIdSetForTableName(fk.ForeignTableName).Add(idValue)
+				idSet.Add(idValue)
 			}
 		}
 	}
@@ -201,44 +182,46 @@ func (c *CacheUtil) AddIdsToForeignTableCache(ctx context.Context, tableName str
 // The function is used along with the Get call in plugin, it returns the record from the cache if it exists
 // otherwise it pulls the records from the data source and adds it to the cache
 func (c *CacheUtil) GetRecordByIdAndBuildCache(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData, tableName string, idToReturn string, columnsMap map[string]*plugin.Column) (interface{}, error) {
-	var tableCache = c.getCacheForTableName(tableName)
 	var keyStruct = c.getKeyStructForTableName(tableName)
+	// Tables without a key struct are not cached, the caller gets the record from the data source
+	if keyStruct == nil || !c.records.hasTable(tableName) {
+		return nil, nil
+	}
 
 	//--------------- Getting values from the cache ------------------//
 
-	if record, exists := tableCache.Get(idToReturn); exists {
-		columnsFound := true
-		if recordCache, ok := record.(*cache.Cache); ok {
-			for column, _ := range columnsMap {
-				if _, exists := recordCache.Get(column); !exists {
-					columnsFound = false
-					break
-				}
-			}
-		}
-		if columnsFound {
-			plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache columns found in cache returning ")
-			return GetResultMapFromCache(record.(*cache.Cache))
-		}
-		plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache columns not found in cache", record)
-		plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache columns not found in  query columns", d.QueryContext.Columns)
-	} else {
-		plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache ID not present in cache 1st check ", idToReturn)
+	columns := getColumnNames(columnsMap)
+	if record, columnsFound := c.records.lookup(tableName, idToReturn, columns); columnsFound {
+		plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache columns found in cache returning ")
+		c.records.recordHit(tableName, true)
+		return record, nil
 	}
+	plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache ID or columns not present in cache 1st check ", idToReturn)
+	c.records.recordHit(tableName, false)
 
 	//--------------- Build cache in batches ------------------//
 	var batches = c.getKeysToPullInBatches(ctx, tableName, c.batchSize, columnsMap)
+	var idSet = c.getIdSetForTableName(tableName)
 
 	var wg sync.WaitGroup
+	batchLimit := make(chan struct{}, maxConcurrentBatches)
 
 	for _, batch := range batches {
 
 		wg.Add(1)
 
-		go func() {
-			DataList, err := keyStruct.BulkDataPullByIds(ctx, d, h, batch, columnsMap)
+		go func(batch []string) {
+			defer wg.Done()
+			batchLimit <- struct{}{}
+			defer func() { <-batchLimit }()
+			formattedIds := make([]string, 0, len(batch))
+			for _, id := range batch {
+				formattedIds = append(formattedIds, c.IdFormatter(id))
+			}
+			DataList, err := keyStruct.BulkDataPullByIds(ctx, d, h, formattedIds, columnsMap)
 			if err != nil {
 				plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache", "results decoding error", err)
+				return
 			}
 
 			for _, record := range *DataList {
@@ -249,10 +232,6 @@ func (c *CacheUtil) GetRecordByIdAndBuildCache(ctx context.Context, d *plugin.Qu
 					if idValue, ok := id.(string); ok {
 						// Setting the value in the cache
 						c.AddRecordToTableCache(ctx, tableName, idValue, record)
-						// tableCache.Set(idValue, record, c.cacheExpiration)
-						// Removing the id from the set
-						// idSet.Remove(idValue)
-
 						c.AddIdsToForeignTableCache(ctx, tableName, record)
 
 					} else {
@@ -262,38 +241,108 @@ func (c *CacheUtil) GetRecordByIdAndBuildCache(ctx context.Context, d *plugin.Qu
 					plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache cache set idString does not exists", id, " value ", record)
 				}
 			}
-			wg.Done()
-		}()
+			// Removing the pulled ids from the set, including ids without a record so they aren't pulled again
+			for _, id := range batch {
+				idSet.Remove(id)
+			}
+		}(batch)
 	}
 	wg.Wait()
 
 	//--------------- Getting values from the cache built------------------//
 
-	if record, exists := tableCache.Get(idToReturn); exists {
-		return GetResultMapFromCache(record.(*cache.Cache))
-	} else {
-		plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache not present in cache ", idToReturn)
+	if record, columnsFound := c.records.lookup(tableName, idToReturn, columns); columnsFound {
+		return record, nil
 	}
+	plugin.Logger(ctx).Debug("salesforce.GetRecordByIdAndBuildCache not present in cache ", idToReturn)
 
 	return nil, nil
 }
 
+// RemoveRecord removes a record from the table cache, e.g. after it was changed. Table names are
+// matched case-insensitively, as they are object API names.
+func (c *CacheUtil) RemoveRecord(tableName string, id string) {
+	c.records.remove(tableName, id)
+}
+
+// Clear removes the cached records and the ids waiting to be prefetched before the cache is replaced, so its
+// records no longer count against a shared budget. Records added by queries still using the cache are ignored.
+func (c *CacheUtil) Clear() {
+	c.records.clear()
+	for _, idSet := range c.tableIdSet {
+		idSet.Clear()
+	}
+}
+
 //--------------- SET  ------------------//
 
-// Replace with time based map with TTL
+// Set holds ids with the time they were added. It is written by list streams and batch
+// goroutines while Get calls read it, so all access goes through its lock.
+type Set struct {
+	mu      sync.RWMutex
+	items   map[string]time.Time
+	maxSize int
+}
 
-type Set map[string]time.Time
+// NewSet returns a set holding at most maxSize ids, or any number of ids if maxSize is 0 or less
+func NewSet(maxSize int) *Set {
+	return &Set{items: make(map[string]time.Time), maxSize: maxSize}
+}
 
-func (s Set) Add(element string) {
-	s[element] = time.Now()
+// Add adds the id unless the set is full, in which case the id is left to be fetched on its own
+func (s *Set) Add(element string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.items[element]; !exists && s.maxSize > 0 && len(s.items) >= s.maxSize {
+		return
+	}
+	s.items[element] = time.Now()
+}
+
+func (s *Set) Remove(element string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, element)
 }
 
-func (s Set) Remove(element string) {
-	delete(s, element)
+func (s *Set) Contains(element string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items[element]
 }
 
-func (s Set) Contains(element string) time.Time {
-	return s[element]
+// Items returns a copy of the ids, which can be ranged over while the set is written
+func (s *Set) Items() map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := make(map[string]time.Time, len(s.items))
+	for element, added := range s.items {
+		items[element] = added
+	}
+	return items
+}
+
+// RemoveOlderThan removes the ids added before the cutoff
+func (s *Set) RemoveOlderThan(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for element, added := range s.items {
+		if added.Before(cutoff) {
+			delete(s.items, element)
+		}
+	}
+}
+
+func (s *Set) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]time.Time)
+}
+
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
 }
 
 //--------------- SET END ------------------//
@@ -320,12 +369,13 @@ func measureTime(ctx context.Context, start time.Time, functionName string) {
 	plugin.Logger(ctx).Debug(fmt.Sprintf("Function %s took %s\n", functionName, time.Since(start)))
 }
 
-func GetResultMapFromCache(record *cache.Cache) (map[string]interface{}, error) {
-	result := make(map[string]interface{})
-	// Iterating through the items in the cache
-	for key, value := range record.Items() {
-		// Adding each item to the result map
-		result[key] = value.Object
+// Stats returns the metrics of the cached records per table
+func (c *CacheUtil) Stats() []TableStats {
+	stats := c.records.stats()
+	for i := range stats {
+		if idSet := c.getIdSetForTableName(stats[i].Name); idSet != nil {
+			stats[i].PendingIds = idSet.Len()
+		}
 	}
-	return result, nil
+	return stats
 }
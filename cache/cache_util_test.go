@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+// Goroutines and iterations of the concurrency tests, run them with go test -race
+const (
+	testGoroutines = 16
+	testIterations = 200
+	testAccounts   = 50
+)
+
+func testContext() context.Context {
+	return context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+}
+
+func accountID(i int) string {
+	return fmt.Sprintf("001%012d", i%testAccounts)
+}
+
+// newTestCacheUtil returns a cache of contacts referencing accounts, fetching accounts named after their id
+func newTestCacheUtil(pulls *int32, limits Limits) *CacheUtil {
+	pullAccounts := func(_ context.Context, _ *plugin.QueryData, _ *plugin.HydrateData, ids []string, _ map[string]*plugin.Column) (*[]map[string]interface{}, error) {
+		atomic.AddInt32(pulls, 1)
+		records := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			records = append(records, map[string]interface{}{"Id": id, "Name": "Account " + id})
+		}
+		return &records, nil
+	}
+	return NewCacheUtil([]KeyStruct{
+		{Name: "Contact", Pk: "Id", Fk: []ForeignKeyStruct{{Key: "AccountId", ForeignTableName: "Account"}}},
+		{Name: "Account", Pk: "Id", BulkDataPullByIds: pullAccounts},
+	}, time.Minute, 10, nil, limits)
+}
+
+func TestSetConcurrentAccess(t *testing.T) {
+	set := NewSet(0)
+	var wg sync.WaitGroup
+	for g := 0; g < testGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < testIterations; i++ {
+				id := accountID(g*testIterations + i)
+				set.Add(id)
+				set.Contains(id)
+				for range set.Items() {
+				}
+				if i%10 == 0 {
+					set.RemoveOlderThan(time.Now().Add(-time.Hour))
+				}
+				set.Remove(accountID(i))
+				set.Len()
+			}
+		}(g)
+	}
+	wg.Wait()
+	if set.Len() > testAccounts {
+		t.Errorf("set has %d ids, want at most %d", set.Len(), testAccounts)
+	}
+}
+
+func TestSetMaxSize(t *testing.T) {
+	set := NewSet(testAccounts / 2)
+	var wg sync.WaitGroup
+	for g := 0; g < testGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < testIterations; i++ {
+				set.Add(fmt.Sprintf("%d-%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	if set.Len() != testAccounts/2 {
+		t.Errorf("set has %d ids, want %d", set.Len(), testAccounts/2)
+	}
+}
+
+func TestCacheUtilConcurrentAccess(t *testing.T) {
+	ctx := testContext()
+	d := &plugin.QueryData{QueryContext: &plugin.QueryContext{}}
+	columns := map[string]*plugin.Column{"Id": {Name: "id"}, "Name": {Name: "name"}}
+	var pulls int32
+	c := newTestCacheUtil(&pulls, Limits{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, testGoroutines*testIterations)
+	for g := 0; g < testGoroutines; g++ {
+		// List streams of contacts adding the ids of their accounts
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < testIterations; i++ {
+				c.AddIdsToForeignTableCache(ctx, "Contact", map[string]interface{}{
+					"Id":        fmt.Sprintf("003%06d%06d", g, i),
+					"AccountId": accountID(g + i),
+				})
+			}
+		}(g)
+
+		// Get calls of the accounts, prefetching the ids added so far in batches
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < testIterations; i++ {
+				id := accountID(g * i)
+				record, err := c.GetRecordByIdAndBuildCache(ctx, d, nil, "Account", id, columns)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				// Accounts are only returned once their id was listed, and then with their own columns
+				if record != nil && record.(map[string]interface{})["Name"] != "Account "+id {
+					errs <- fmt.Errorf("account %s = %v", id, record)
+				}
+				c.Stats()
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for _, stats := range c.Stats() {
+		if stats.Name == "Account" && stats.Records > testAccounts {
+			t.Errorf("%d accounts cached, want at most %d", stats.Records, testAccounts)
+		}
+	}
+	if atomic.LoadInt32(&pulls) == 0 {
+		t.Error("no accounts were prefetched")
+	}
+}
+
+func TestCacheUtilConcurrentAccessSharedBudget(t *testing.T) {
+	ctx := testContext()
+	budget := NewBudget(20 * recordOverheadBytes)
+	var pulls int32
+	caches := []*CacheUtil{
+		newTestCacheUtil(&pulls, Limits{MaxRecordsPerTable: 10, Budget: budget}),
+		newTestCacheUtil(&pulls, Limits{MaxRecordsPerTable: 10, Budget: budget}),
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < testGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			c := caches[g%len(caches)]
+			for i := 0; i < testIterations; i++ {
+				c.AddIdsToForeignTableCache(ctx, "Account", map[string]interface{}{"Id": accountID(g + i), "Name": "Acme"})
+				c.Stats()
+				if i%50 == 0 {
+					budget.SetMaxBytes(budget.MaxBytes())
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if used := budget.UsedBytes(); used > budget.MaxBytes() {
+		t.Errorf("used bytes = %d, want at most %d", used, budget.MaxBytes())
+	}
+	for _, c := range caches {
+		for _, stats := range c.Stats() {
+			if stats.Records > 10 {
+				t.Errorf("%d %s records cached, want at most 10", stats.Records, stats.Name)
+			}
+		}
+		c.Clear()
+	}
+	if used := budget.UsedBytes(); used != 0 {
+		t.Errorf("used bytes = %d after the caches were cleared, want 0", used)
+	}
+}
+
+func TestGetRecordByIdAndBuildCacheBoundsConcurrentBatches(t *testing.T) {
+	ctx := testContext()
+	d := &plugin.QueryData{QueryContext: &plugin.QueryContext{}}
+	var inFlight, maxInFlight int32
+	pullAccounts := func(_ context.Context, _ *plugin.QueryData, _ *plugin.HydrateData, ids []string, _ map[string]*plugin.Column) (*[]map[string]interface{}, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		records := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			records = append(records, map[string]interface{}{"Id": id, "Name": "Account " + id})
+		}
+		return &records, nil
+	}
+	c := NewCacheUtil([]KeyStruct{
+		{Name: "Contact", Pk: "Id", Fk: []ForeignKeyStruct{{Key: "AccountId", ForeignTableName: "Account"}}},
+		{Name: "Account", Pk: "Id", BulkDataPullByIds: pullAccounts},
+	}, time.Minute, 1, nil, Limits{})
+
+	// Each pending account is a batch of its own
+	for i := 0; i < 4*maxConcurrentBatches; i++ {
+		c.AddIdsToForeignTableCache(ctx, "Contact", map[string]interface{}{"Id": fmt.Sprintf("003%012d", i), "AccountId": fmt.Sprintf("001%012d", i)})
+	}
+	columns := map[string]*plugin.Column{"Id": {Name: "id"}, "Name": {Name: "name"}}
+	record, err := c.GetRecordByIdAndBuildCache(ctx, d, nil, "Account", fmt.Sprintf("001%012d", 0), columns)
+	if err != nil || record == nil {
+		t.Fatalf("GetRecordByIdAndBuildCache = %v, %v, want the prefetched account", record, err)
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max > maxConcurrentBatches {
+		t.Errorf("%d batches pulled in parallel, want at most %d", max, maxConcurrentBatches)
+	}
+}
@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"container/list"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Estimated bytes of bookkeeping per cached record and per column, on top of the column values
+const (
+	recordOverheadBytes = 200
+	columnOverheadBytes = 48
+)
+
+// Budget bounds the estimated memory of the record caches sharing it. Once it is exceeded, the least
+// recently used records of any of the caches are evicted. A budget of 0 or less is unbounded.
+type Budget struct {
+	// guards the budget and the record stores sharing it, as evicting records to fit the budget
+	// touches the stores of other caches
+	mu        sync.Mutex
+	maxBytes  int64
+	usedBytes int64
+	// *recordEntry of all caches, the most recently used first
+	lru *list.List
+}
+
+func NewBudget(maxBytes int64) *Budget {
+	return &Budget{maxBytes: maxBytes, lru: list.New()}
+}
+
+// SetMaxBytes changes the budget, evicting records if they no longer fit
+func (b *Budget) SetMaxBytes(maxBytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxBytes = maxBytes
+	b.evictLocked()
+}
+
+func (b *Budget) MaxBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.maxBytes
+}
+
+func (b *Budget) UsedBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usedBytes
+}
+
+// evictLocked removes the least recently used records until the budget fits. The caller holds b.mu.
+func (b *Budget) evictLocked() {
+	for b.maxBytes > 0 && b.usedBytes > b.maxBytes && b.lru.Len() > 0 {
+		entry := b.lru.Back().Value.(*recordEntry)
+		entry.store.removeLocked(entry, true)
+	}
+}
+
+// recordEntry is a cached record with the columns fetched so far
+type recordEntry struct {
+	store      *recordStore
+	table      string
+	id         string
+	columns    map[string]interface{}
+	size       int64
+	expires    time.Time
+	budgetElem *list.Element
+	tableElem  *list.Element
+}
+
+// tableRecords holds the records of a table in least recently used order, with the metrics of the table
+type tableRecords struct {
+	entries   map[string]*recordEntry
+	lru       *list.List
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// TableStats are the metrics of the cached records of a table
+type TableStats struct {
+	Name               string
+	Records            int
+	Bytes              int64
+	MaxRecordsPerTable int
+	Hits               int64
+	Misses             int64
+	Evictions          int64
+	// ids of the table waiting to be prefetched
+	PendingIds int
+}
+
+// recordStore holds the records of a CacheUtil. All access goes through mu, which is the lock of its budget.
+type recordStore struct {
+	mu                 *sync.Mutex
+	budget             *Budget
+	maxRecordsPerTable int
+	expiration         time.Duration
+	nextSweep          time.Time
+	tables             map[string]*tableRecords
+	// set once the store is cleared, so queries still using a replaced cache don't add records to the budget
+	closed bool
+}
+
+func newRecordStore(tableKeyStruct []KeyStruct, budget *Budget, maxRecordsPerTable int, expiration time.Duration) *recordStore {
+	store := &recordStore{
+		mu:                 &budget.mu,
+		budget:             budget,
+		maxRecordsPerTable: maxRecordsPerTable,
+		expiration:         expiration,
+		nextSweep:          time.Now().Add(expiration),
+		tables:             make(map[string]*tableRecords),
+	}
+	for _, keyStruct := range tableKeyStruct {
+		store.tables[keyStruct.Name] = &tableRecords{entries: make(map[string]*recordEntry), lru: list.New()}
+	}
+	return store
+}
+
+func (s *recordStore) hasTable(table string) bool {
+	_, ok := s.tables[table]
+	return ok
+}
+
+// getLocked returns the entry of a record that hasn't expired and marks it as recently used. The caller holds s.mu.
+func (s *recordStore) getLocked(table string, id string) *recordEntry {
+	records, ok := s.tables[table]
+	if !ok {
+		return nil
+	}
+	entry, ok := records.entries[id]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		s.removeLocked(entry, false)
+		return nil
+	}
+	// if required element is already in cache increment TTL for the element in cache
+	entry.expires = time.Now().Add(s.expiration)
+	s.budget.lru.MoveToFront(entry.budgetElem)
+	records.lru.MoveToFront(entry.tableElem)
+	return entry
+}
+
+// lookup returns a copy of the record and whether all the columns are cached
+func (s *recordStore) lookup(table string, id string, columns []string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.getLocked(table, id)
+	if entry == nil {
+		return nil, false
+	}
+	for _, column := range columns {
+		if _, exists := entry.columns[column]; !exists {
+			return nil, false
+		}
+	}
+	record := make(map[string]interface{}, len(entry.columns))
+	for column, value := range entry.columns {
+		record[column] = value
+	}
+	return record, true
+}
+
+// put adds the columns of a record, then evicts the least recently used records if the table
+// or the budget are over their limits
+func (s *recordStore) put(table string, id string, columns map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, ok := s.tables[table]
+	if !ok || s.closed {
+		return
+	}
+	s.sweepLocked()
+	entry := s.getLocked(table, id)
+	if entry == nil {
+		entry = &recordEntry{store: s, table: table, id: id, columns: make(map[string]interface{}), size: recordOverheadBytes + int64(len(id))}
+		entry.expires = time.Now().Add(s.expiration)
+		entry.budgetElem = s.budget.lru.PushFront(entry)
+		entry.tableElem = records.lru.PushFront(entry)
+		records.entries[id] = entry
+		records.bytes += entry.size
+		s.budget.usedBytes += entry.size
+	}
+
+	for column, value := range columns {
+		size := columnOverheadBytes + int64(len(column)) + estimateSize(reflect.ValueOf(value))
+		if old, exists := entry.columns[column]; exists {
+			size -= columnOverheadBytes + int64(len(column)) + estimateSize(reflect.ValueOf(old))
+		}
+		entry.columns[column] = value
+		entry.size += size
+		records.bytes += size
+		s.budget.usedBytes += size
+	}
+
+	for s.maxRecordsPerTable > 0 && len(records.entries) > s.maxRecordsPerTable {
+		s.removeLocked(records.lru.Back().Value.(*recordEntry), true)
+	}
+	s.budget.evictLocked()
+}
+
+// sweepLocked removes the expired records, at most once per expiration period. The caller holds s.mu.
+func (s *recordStore) sweepLocked() {
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(s.expiration)
+	for _, records := range s.tables {
+		// the least recently used records are at the back and expire first
+		for records.lru.Len() > 0 {
+			entry := records.lru.Back().Value.(*recordEntry)
+			if now.Before(entry.expires) {
+				break
+			}
+			s.removeLocked(entry, false)
+		}
+	}
+}
+
+// removeLocked removes a record from its table and the budget. The caller holds s.mu.
+func (s *recordStore) removeLocked(entry *recordEntry, evicted bool) {
+	records := s.tables[entry.table]
+	delete(records.entries, entry.id)
+	records.lru.Remove(entry.tableElem)
+	records.bytes -= entry.size
+	s.budget.lru.Remove(entry.budgetElem)
+	s.budget.usedBytes -= entry.size
+	if evicted {
+		records.evictions++
+	}
+}
+
+// remove removes the record of a table, matching the table name case-insensitively
+func (s *recordStore) remove(table string, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, records := range s.tables {
+		if !strings.EqualFold(name, table) {
+			continue
+		}
+		if entry, ok := records.entries[id]; ok {
+			s.removeLocked(entry, false)
+		}
+	}
+}
+
+// clear removes all the records, releasing their memory from the budget. Records put afterwards are ignored.
+func (s *recordStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, records := range s.tables {
+		for records.lru.Len() > 0 {
+			s.removeLocked(records.lru.Back().Value.(*recordEntry), false)
+		}
+	}
+}
+
+// recordHit counts a Get call served from the cache, or one that had to fetch the record if hit is false
+func (s *recordStore) recordHit(table string, hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if records, ok := s.tables[table]; ok {
+		if hit {
+			records.hits++
+		} else {
+			records.misses++
+		}
+	}
+}
+
+// stats returns the metrics of the tables sorted by name
+func (s *recordStore) stats() []TableStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]TableStats, 0, len(s.tables))
+	for name, records := range s.tables {
+		stats = append(stats, TableStats{
+			Name:               name,
+			Records:            len(records.entries),
+			Bytes:              records.bytes,
+			MaxRecordsPerTable: s.maxRecordsPerTable,
+			Hits:               records.hits,
+			Misses:             records.misses,
+			Evictions:          records.evictions,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// estimateSize returns the approximate bytes held by a decoded JSON value
+func estimateSize(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return 16 + int64(v.Len())
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return 8
+		}
+		return 8 + estimateSize(v.Elem())
+	case reflect.Slice, reflect.Array:
+		size := int64(24)
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i))
+		}
+		return size
+	case reflect.Map:
+		size := int64(48)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimateSize(iter.Key()) + estimateSize(iter.Value())
+		}
+		return size
+	default:
+		return int64(v.Type().Size())
+	}
+}
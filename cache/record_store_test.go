@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestRecordStore returns a store of accounts sharing the budget
+func newTestRecordStore(budget *Budget, maxRecordsPerTable int) *recordStore {
+	return newRecordStore([]KeyStruct{{Name: "Account", Pk: "Id"}}, budget, maxRecordsPerTable, time.Hour)
+}
+
+// putAccount adds an account, which all have the same size
+func putAccount(s *recordStore, id string) {
+	s.put("Account", id, map[string]interface{}{"Id": id, "Name": "Acme"})
+}
+
+// cachedAccounts returns which of the accounts are cached
+func cachedAccounts(s *recordStore, ids ...string) map[string]bool {
+	cached := map[string]bool{}
+	for _, id := range ids {
+		_, cached[id] = s.lookup("Account", id, []string{"Name"})
+	}
+	return cached
+}
+
+func TestRecordStoreEvictsLeastRecentlyUsedRecordOfTable(t *testing.T) {
+	s := newTestRecordStore(NewBudget(0), 2)
+	putAccount(s, "001000000000001AAA")
+	putAccount(s, "001000000000002AAA")
+	// Reading the first account makes the second one the least recently used
+	cachedAccounts(s, "001000000000001AAA")
+	putAccount(s, "001000000000003AAA")
+
+	got := cachedAccounts(s, "001000000000001AAA", "001000000000002AAA", "001000000000003AAA")
+	want := map[string]bool{"001000000000001AAA": true, "001000000000002AAA": false, "001000000000003AAA": true}
+	for id, cached := range want {
+		if got[id] != cached {
+			t.Errorf("account %s cached = %v, want %v", id, got[id], cached)
+		}
+	}
+	if stats := s.stats(); stats[0].Evictions != 1 || stats[0].Records != 2 {
+		t.Errorf("stats = %+v, want 2 records and 1 eviction", stats[0])
+	}
+}
+
+func TestBudgetEvictsLeastRecentlyUsedRecordOfAnyStore(t *testing.T) {
+	budget := NewBudget(0)
+	first := newTestRecordStore(budget, 0)
+	second := newTestRecordStore(budget, 0)
+
+	putAccount(first, "001000000000001AAA")
+	recordBytes := budget.UsedBytes()
+	budget.SetMaxBytes(2 * recordBytes)
+	putAccount(second, "001000000000002AAA")
+	// Reading the account of the first store makes the account of the second store the least recently used
+	cachedAccounts(first, "001000000000001AAA")
+	putAccount(first, "001000000000003AAA")
+
+	if got := cachedAccounts(first, "001000000000001AAA", "001000000000003AAA"); !got["001000000000001AAA"] || !got["001000000000003AAA"] {
+		t.Errorf("accounts of the first store cached = %v, want both", got)
+	}
+	if got := cachedAccounts(second, "001000000000002AAA"); got["001000000000002AAA"] {
+		t.Error("least recently used account of the second store is still cached")
+	}
+	if used := budget.UsedBytes(); used != 2*recordBytes {
+		t.Errorf("used bytes = %d, want %d", used, 2*recordBytes)
+	}
+
+	// Lowering the budget evicts the least recently used records first
+	budget.SetMaxBytes(recordBytes)
+	if got := cachedAccounts(first, "001000000000001AAA", "001000000000003AAA"); got["001000000000001AAA"] || !got["001000000000003AAA"] {
+		t.Errorf("accounts cached after lowering the budget = %v, want only the most recently used", got)
+	}
+}
+
+func TestRecordStoreIgnoresRecordsAfterClear(t *testing.T) {
+	budget := NewBudget(0)
+	s := newTestRecordStore(budget, 0)
+	putAccount(s, "001000000000001AAA")
+	s.clear()
+	if used := budget.UsedBytes(); used != 0 {
+		t.Fatalf("used bytes = %d after clear, want 0", used)
+	}
+
+	// A query still using the replaced cache doesn't add to the shared budget
+	putAccount(s, "001000000000002AAA")
+	if used := budget.UsedBytes(); used != 0 {
+		t.Errorf("used bytes = %d after a put on a cleared store, want 0", used)
+	}
+	if got := cachedAccounts(s, "001000000000002AAA"); got["001000000000002AAA"] {
+		t.Error("record put on a cleared store is cached")
+	}
+}
@@ -0,0 +1,195 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// actionResult is the outcome of a create, update or delete call on a record
+type actionResult struct {
+	ID      string
+	Success bool
+	Errors  []string
+}
+
+// API names of objects and record ids are placed in the request path, so anything else is rejected
+var sObjectNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+var recordIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{15}([A-Za-z0-9]{3})?$`)
+
+// checkWritesAllowed:: returns an error unless allow_writes is set in the connection config
+func checkWritesAllowed(d *plugin.QueryData) error {
+	config := GetConfig(d.Connection)
+	if config.AllowWrites == nil || !*config.AllowWrites {
+		return fmt.Errorf("writes are disabled, set allow_writes = true in the %s connection config to use %s", d.Connection.Name, d.Table.Name)
+	}
+	return nil
+}
+
+// getActionObject:: returns the validated object qual of an action table
+func getActionObject(d *plugin.QueryData) (string, error) {
+	object := strings.TrimSpace(d.EqualsQualString("object"))
+	if !sObjectNamePattern.MatchString(object) {
+		return "", fmt.Errorf("invalid object name %q", object)
+	}
+	return object, nil
+}
+
+// getActionID:: returns the validated id qual of an action table
+func getActionID(d *plugin.QueryData) (string, error) {
+	id := strings.TrimSpace(d.EqualsQualString("id"))
+	if !recordIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid record id %q", id)
+	}
+	return id, nil
+}
+
+// getActionFields:: returns the fields qual of an action table as request body. The qual must be a JSON
+// object of field API names and values, e.g. {"Name": "Acme", "Industry": "Banking"}.
+func getActionFields(d *plugin.QueryData) ([]byte, error) {
+	qual := d.EqualsQuals["fields"]
+	if qual == nil {
+		return nil, fmt.Errorf("fields must be set")
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(qual.GetJsonbValue()), &fields); err != nil {
+		return nil, fmt.Errorf("fields must be a JSON object: %v", err)
+	}
+	return json.Marshal(fields)
+}
+
+// runSObjectAction:: sends a request to a sObject resource and streams the result
+func runSObjectAction(ctx context.Context, d *plugin.QueryData, method string, object string, id string, body []byte) error {
+	result, err := sendSObjectAction(ctx, d, method, object, id, body)
+	if err != nil {
+		return err
+	}
+	d.StreamListItem(ctx, result)
+	return nil
+}
+
+// sendSObjectAction:: sends a request to the sObject resource of the object, or of the record if id is set.
+// Errors returned by Salesforce, e.g. validation rule failures, are returned in the result instead of failing
+// the query. Once a record is updated or deleted, it is removed from the record cache of the connection.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/using_resources_working_with_records.htm
+func sendSObjectAction(ctx context.Context, d *plugin.QueryData, method string, object string, id string, body []byte) (actionResult, error) {
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+	}
+	path := sObjectPath(d, object, id)
+	plugin.Logger(ctx).Info("salesforce.sendSObjectAction", "table_name", d.Table.Name, "method", method, "path", path)
+
+	data, _, err := restRequest(ctx, d, method, path, body, contentType)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.sendSObjectAction", "table_name", d.Table.Name, "request_error", err)
+		return actionResult{ID: id, Success: false, Errors: []string{err.Error()}}, nil
+	}
+
+	result := actionResult{ID: id, Success: true, Errors: []string{}}
+	// Create returns the new record id, while update and delete return no content
+	if len(data) > 0 {
+		var created struct {
+			ID      string        `json:"id"`
+			Success bool          `json:"success"`
+			Errors  []interface{} `json:"errors"`
+		}
+		if err = json.Unmarshal(data, &created); err != nil {
+			return actionResult{}, err
+		}
+		result.ID = created.ID
+		result.Success = created.Success
+		for _, e := range created.Errors {
+			result.Errors = append(result.Errors, fmt.Sprint(e))
+		}
+	}
+	if id != "" && result.Success {
+		removeCachedRecord(ctx, d.Connection.Name, object, getCaseSafeID(id))
+	}
+	return result, nil
+}
+
+// getCaseSafeID:: returns the 18 character case-insensitive id of a 15 character record id, which is the
+// format of the ids returned by the API. The suffix encodes which of the characters are uppercase.
+func getCaseSafeID(id string) string {
+	if len(id) != 15 {
+		return id
+	}
+	const suffixChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+	suffix := make([]byte, 3)
+	for i := range suffix {
+		flags := 0
+		for j := 0; j < 5; j++ {
+			if c := id[i*5+j]; c >= 'A' && c <= 'Z' {
+				flags |= 1 << j
+			}
+		}
+		suffix[i] = suffixChars[flags]
+	}
+	return id + string(suffix)
+}
+
+// sObjectPath:: returns the path of the sObject resource of the object, or of a record if id is set
+func sObjectPath(d *plugin.QueryData, object string, id string) string {
+	path := fmt.Sprintf("services/data/v%s/sobjects/%s", getAPIVersion(GetConfig(d.Connection)), object)
+	if id != "" {
+		path = fmt.Sprintf("%s/%s", path, id)
+	}
+	return path
+}
+
+// List hydrates of the action tables
+
+func createSalesforceRecord(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	if err := checkWritesAllowed(d); err != nil {
+		return nil, err
+	}
+	object, err := getActionObject(d)
+	if err != nil {
+		return nil, err
+	}
+	body, err := getActionFields(d)
+	if err != nil {
+		return nil, err
+	}
+	return nil, runSObjectAction(ctx, d, http.MethodPost, object, "", body)
+}
+
+func updateSalesforceRecord(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	if err := checkWritesAllowed(d); err != nil {
+		return nil, err
+	}
+	object, err := getActionObject(d)
+	if err != nil {
+		return nil, err
+	}
+	id, err := getActionID(d)
+	if err != nil {
+		return nil, err
+	}
+	body, err := getActionFields(d)
+	if err != nil {
+		return nil, err
+	}
+	return nil, runSObjectAction(ctx, d, http.MethodPatch, object, id, body)
+}
+
+func deleteSalesforceRecord(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	if err := checkWritesAllowed(d); err != nil {
+		return nil, err
+	}
+	object, err := getActionObject(d)
+	if err != nil {
+		return nil, err
+	}
+	id, err := getActionID(d)
+	if err != nil {
+		return nil, err
+	}
+	return nil, runSObjectAction(ctx, d, http.MethodDelete, object, id, nil)
+}
@@ -0,0 +1,157 @@
+package salesforce
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// actionRequest is a request received by the fake org of the action tests
+type actionRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+}
+
+func TestSendSObjectAction(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		id          string
+		body        []byte
+		status      int
+		response    string
+		want        actionRequest
+		wantResult  actionResult
+		wantErrCode string
+	}{
+		{
+			name:       "create",
+			method:     http.MethodPost,
+			body:       []byte(`{"Name":"Acme"}`),
+			status:     http.StatusCreated,
+			response:   `{"id":"001000000000001AAA","success":true,"errors":[]}`,
+			want:       actionRequest{method: http.MethodPost, path: "/services/data/v58.0/sobjects/Account", contentType: "application/json", body: `{"Name":"Acme"}`},
+			wantResult: actionResult{ID: "001000000000001AAA", Success: true, Errors: []string{}},
+		},
+		{
+			name:       "update",
+			method:     http.MethodPatch,
+			id:         "001000000000001AAA",
+			body:       []byte(`{"Industry":"Banking"}`),
+			status:     http.StatusNoContent,
+			want:       actionRequest{method: http.MethodPatch, path: "/services/data/v58.0/sobjects/Account/001000000000001AAA", contentType: "application/json", body: `{"Industry":"Banking"}`},
+			wantResult: actionResult{ID: "001000000000001AAA", Success: true, Errors: []string{}},
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			id:         "001000000000001AAA",
+			status:     http.StatusNoContent,
+			want:       actionRequest{method: http.MethodDelete, path: "/services/data/v58.0/sobjects/Account/001000000000001AAA"},
+			wantResult: actionResult{ID: "001000000000001AAA", Success: true, Errors: []string{}},
+		},
+		{
+			name:        "validation error",
+			method:      http.MethodPatch,
+			id:          "001000000000001AAA",
+			body:        []byte(`{"Name":""}`),
+			status:      http.StatusBadRequest,
+			response:    `[{"message":"Required fields are missing: [Name]","errorCode":"REQUIRED_FIELD_MISSING","fields":["Name"]}]`,
+			want:        actionRequest{method: http.MethodPatch, path: "/services/data/v58.0/sobjects/Account/001000000000001AAA", contentType: "application/json", body: `{"Name":""}`},
+			wantResult:  actionResult{ID: "001000000000001AAA", Success: false},
+			wantErrCode: "REQUIRED_FIELD_MISSING",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := []actionRequest{}
+			f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				requests = append(requests, actionRequest{method: r.Method, path: r.URL.Path, contentType: r.Header.Get("Content-Type"), body: string(body)})
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			})
+			d := f.queryData("action", salesforceConfig{APIVersion: stringPtr("58.0"), AllowWrites: boolPtr(true)})
+
+			result, err := sendSObjectAction(testContext(), d, tt.method, "Account", tt.id, tt.body)
+			if err != nil {
+				t.Fatalf("sendSObjectAction: %v", err)
+			}
+			// Writes are sent once, even if they fail
+			if len(requests) != 1 || requests[0] != tt.want {
+				t.Errorf("requests = %+v, want %+v", requests, tt.want)
+			}
+			if result.ID != tt.wantResult.ID || result.Success != tt.wantResult.Success {
+				t.Errorf("result = %+v, want %+v", result, tt.wantResult)
+			}
+			if tt.wantErrCode == "" && len(result.Errors) != 0 {
+				t.Errorf("errors = %v, want none", result.Errors)
+			}
+			if tt.wantErrCode != "" && (len(result.Errors) != 1 || !strings.Contains(result.Errors[0], tt.wantErrCode)) {
+				t.Errorf("errors = %v, want %s", result.Errors, tt.wantErrCode)
+			}
+		})
+	}
+}
+
+func TestSendSObjectActionRemovesCachedRecord(t *testing.T) {
+	ctx := testContext()
+	updateStatus := http.StatusNoContent
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "SELECT Id FROM Organization" {
+			_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"00D000000000004"}]}`))
+			return
+		}
+		w.WriteHeader(updateStatus)
+		if updateStatus != http.StatusNoContent {
+			_, _ = w.Write([]byte(`[{"message":"entity is locked","errorCode":"ENTITY_IS_LOCKED"}]`))
+		}
+	})
+	d := f.queryData("action_cache", salesforceConfig{APIVersion: stringPtr("58.0"), AllowWrites: boolPtr(true)})
+	setForeignKeyGraph(d.Connection.Name, []cache.KeyStruct{{Name: "Account", Pk: "Id"}})
+	columns := map[string]*plugin.Column{"Id": {Name: "id"}, "Name": {Name: "name"}}
+
+	recordCache := getRecordCache(ctx, d)
+	recordCache.AddIdsToForeignTableCache(ctx, "Account", map[string]interface{}{"Id": "001A0000006Vm9rIAC", "Name": "Acme"})
+
+	// A failed update leaves the cached record as is
+	updateStatus = http.StatusBadRequest
+	if _, err := sendSObjectAction(ctx, d, http.MethodPatch, "Account", "001A0000006Vm9rIAC", []byte(`{"Name":"Globex"}`)); err != nil {
+		t.Fatalf("sendSObjectAction: %v", err)
+	}
+	if record, _ := recordCache.GetRecordByIdAndBuildCache(ctx, d, nil, "Account", "001A0000006Vm9rIAC", columns); record == nil {
+		t.Fatal("record was removed from the cache although the update failed")
+	}
+
+	// The record is cached with its 18 character id, while the update may use the 15 character id
+	updateStatus = http.StatusNoContent
+	if _, err := sendSObjectAction(ctx, d, http.MethodPatch, "account", "001A0000006Vm9r", []byte(`{"Name":"Globex"}`)); err != nil {
+		t.Fatalf("sendSObjectAction: %v", err)
+	}
+	if record, _ := recordCache.GetRecordByIdAndBuildCache(ctx, d, nil, "Account", "001A0000006Vm9rIAC", columns); record != nil {
+		t.Errorf("cached record = %v after the update, want none", record)
+	}
+}
+
+func TestGetCaseSafeID(t *testing.T) {
+	tests := map[string]string{
+		"001A0000006Vm9r":    "001A0000006Vm9rIAC",
+		"001A0000006Vm9rIAC": "001A0000006Vm9rIAC",
+		"003000000000001":    "003000000000001AAA",
+	}
+	for id, want := range tests {
+		if got := getCaseSafeID(id); got != want {
+			t.Errorf("getCaseSafeID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Daily API request usage in the Sforce-Limit-Info header, e.g. "api-usage=18/15000".
+// The per-app-api-usage entry of connected apps is not matched.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_api_usage.htm
+var apiUsagePattern = regexp.MustCompile(`(?:^|[\s,;])api-usage=(\d+)/(\d+)`)
+
+// Time after which a reported API usage is no longer used to check the budget. Salesforce only reports the
+// usage in responses, so once the budget blocks requests, an old reading lets the next request through to
+// find out whether the usage went down, e.g. after other integrations stopped.
+const apiUsageTTL = 5 * time.Minute
+
+// apiUsage is the daily API request usage of the organization, as last reported by Salesforce
+type apiUsage struct {
+	mu       sync.RWMutex
+	used     int64
+	max      int64
+	reported time.Time
+}
+
+// record:: updates the usage from a Sforce-Limit-Info header value, ignoring values without api-usage
+func (u *apiUsage) record(header string) {
+	match := apiUsagePattern.FindStringSubmatch(header)
+	if match == nil {
+		return
+	}
+	used, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return
+	}
+	max, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil || max <= 0 {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.used = used
+	u.max = max
+	u.reported = time.Now()
+}
+
+// remainingPercent:: returns the percentage of the daily API requests that remain, or false if no
+// response with the Sforce-Limit-Info header was received within apiUsageTTL
+func (u *apiUsage) remainingPercent() (float64, int64, int64, bool) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.max == 0 || time.Since(u.reported) > apiUsageTTL {
+		return 0, 0, 0, false
+	}
+	return float64(u.max-u.used) * 100 / float64(u.max), u.used, u.max, true
+}
+
+// apiUsageTransport records the API usage reported in every response
+type apiUsageTransport struct {
+	base  http.RoundTripper
+	usage *apiUsage
+}
+
+func (t *apiUsageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		t.usage.record(resp.Header.Get("Sforce-Limit-Info"))
+	}
+	return resp, err
+}
+
+// HTTP clients tracking the API usage of their responses, keyed by connection name. Clients replaced when
+// a session is refreshed share the HTTP client of their connection, so the reported usage isn't lost.
+var connectionHTTPClients sync.Map
+
+// getHTTPClient:: returns the HTTP client of the connection, for requests not sent through simpleforce
+func getHTTPClient(connectionName string) *http.Client {
+	if httpClient, ok := connectionHTTPClients.Load(connectionName); ok {
+		return httpClient.(*http.Client)
+	}
+	httpClient, _ := connectionHTTPClients.LoadOrStore(connectionName, &http.Client{Transport: &apiUsageTransport{base: http.DefaultTransport, usage: &apiUsage{}}})
+	return httpClient.(*http.Client)
+}
+
+// trackAPIUsage:: makes the salesforce client send its requests through the HTTP client of the connection
+func trackAPIUsage(connectionName string, client *simpleforce.Client) {
+	client.SetHttpClient(getHTTPClient(connectionName))
+}
+
+// getAPIUsage:: returns the API usage tracked for the connection
+func getAPIUsage(connectionName string) *apiUsage {
+	return getHTTPClient(connectionName).Transport.(*apiUsageTransport).usage
+}
+
+// checkAPIBudget:: fails fast if the remaining daily API requests last reported by Salesforce are below
+// min_api_remaining_percent, so the plugin doesn't use up the allocation shared with other integrations.
+// Readings older than apiUsageTTL are ignored, so a blocked connection recovers once the usage goes down.
+func checkAPIBudget(ctx context.Context, d *plugin.QueryData) error {
+	config := GetConfig(d.Connection)
+	if config.MinAPIRemainingPercent == nil {
+		return nil
+	}
+	remaining, used, max, ok := getAPIUsage(d.Connection.Name).remainingPercent()
+	if !ok || remaining >= float64(*config.MinAPIRemainingPercent) {
+		return nil
+	}
+
+	plugin.Logger(ctx).Warn("salesforce.checkAPIBudget", "table_name", d.Table.Name, "api_usage", used, "api_max", max)
+	return fmt.Errorf("only %.1f%% of the daily API requests remain (%d of %d used), which is below min_api_remaining_percent = %d", remaining, used, max, *config.MinAPIRemainingPercent)
+}
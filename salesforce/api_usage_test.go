@@ -0,0 +1,93 @@
+package salesforce
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckAPIBudgetIsPerConnection(t *testing.T) {
+	var requests atomic.Int32
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Sforce-Limit-Info", "api-usage=950/1000")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	minRemaining := 10
+	config := salesforceConfig{MinAPIRemainingPercent: &minRemaining}
+	d := f.queryData("budget", config)
+	ctx := testContext()
+
+	// Nothing is known about the usage before the first response
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, ""); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, ""); err == nil {
+		t.Fatal("request with 5% of the daily API requests remaining succeeded, want an error")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("requests = %d, want 1 as the budget is checked before sending", got)
+	}
+
+	// The usage belongs to the connection, so it outlives the client replaced by a session refresh
+	client, _ := connectRaw(ctx, d.ConnectionCache, d.Connection)
+	if _, err := refreshConnection(ctx, d.ConnectionCache, d.Connection, client); err != nil {
+		t.Fatalf("refreshConnection: %v", err)
+	}
+	if err := checkAPIBudget(ctx, d); err == nil {
+		t.Error("checkAPIBudget after a session refresh succeeded, want an error")
+	}
+
+	// Other connections have their own usage
+	other := f.queryData("budget_other", config)
+	if _, _, err := restRequest(ctx, other, http.MethodGet, "services/data/v58.0/limits", nil, ""); err != nil {
+		t.Errorf("request of another connection: %v", err)
+	}
+}
+
+func TestCheckAPIBudgetRecovers(t *testing.T) {
+	var apiUsed atomic.Int32
+	apiUsed.Store(950)
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Sforce-Limit-Info", fmt.Sprintf("api-usage=%d/1000", apiUsed.Load()))
+		if r.URL.Path == "/services/data/v58.0/limits" {
+			_, _ = w.Write([]byte(`{"DailyApiRequests":{"Max":1000,"Remaining":900}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	minRemaining := 10
+	d := f.queryData("budget_recovers", salesforceConfig{APIVersion: stringPtr("58.0"), MinAPIRemainingPercent: &minRemaining})
+	ctx := testContext()
+
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/sobjects", nil, ""); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := checkAPIBudget(ctx, d); err == nil {
+		t.Fatal("checkAPIBudget with 5% of the daily API requests remaining succeeded, want an error")
+	}
+
+	// The org limits are listed regardless of the budget, and refresh the usage it is checked against
+	apiUsed.Store(100)
+	if _, err := getOrgLimits(ctx, d); err != nil {
+		t.Fatalf("getOrgLimits: %v", err)
+	}
+	if err := checkAPIBudget(ctx, d); err != nil {
+		t.Errorf("checkAPIBudget after the usage went down: %v", err)
+	}
+
+	// A reading older than the ttl doesn't block requests
+	apiUsed.Store(950)
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/sobjects", nil, ""); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	usage := getAPIUsage(d.Connection.Name)
+	usage.mu.Lock()
+	usage.reported = time.Now().Add(-apiUsageTTL - time.Second)
+	usage.mu.Unlock()
+	if err := checkAPIBudget(ctx, d); err != nil {
+		t.Errorf("checkAPIBudget with a usage reported before the ttl: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package salesforce
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Supported authentication flows, selected from the fields set in the connection config
+const (
+	authModePassword          = "password"
+	authModeJWTBearer         = "jwt_bearer"
+	authModeClientCredentials = "client_credentials"
+)
+
+// Lifetime of the JWT assertion sent to the token endpoint. Salesforce rejects
+// assertions valid for more than 3 minutes.
+const jwtAssertionLifetime = 3 * time.Minute
+
+// oauthTokenResponse is the payload returned by the /services/oauth2/token endpoint
+type oauthTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	InstanceURL      string `json:"instance_url"`
+	ID               string `json:"id"`
+	TokenType        string `json:"token_type"`
+	IssuedAt         string `json:"issued_at"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// getAuthMode:: returns the authentication flow to use based on the fields set in the connection config
+// - consumer_key and private_key_file => OAuth 2.0 JWT bearer flow
+// - client_id and client_secret => OAuth 2.0 client credentials flow
+// - otherwise => username/password login
+func getAuthMode(config salesforceConfig) string {
+	if config.ConsumerKey != nil && config.PrivateKeyFile != nil {
+		return authModeJWTBearer
+	}
+	if config.ClientId != nil && config.ClientSecret != nil {
+		return authModeClientCredentials
+	}
+	return authModePassword
+}
+
+// validateAuthConfig:: returns an error if only one of the fields of the JWT bearer flow is set, which would
+// otherwise silently fall back to another flow
+func validateAuthConfig(config salesforceConfig) error {
+	if config.ConsumerKey != nil && config.PrivateKeyFile == nil {
+		return fmt.Errorf("'private_key_file' must be set in the connection configuration to use 'consumer_key'")
+	}
+	if config.PrivateKeyFile != nil && config.ConsumerKey == nil {
+		return fmt.Errorf("'consumer_key' must be set in the connection configuration to use 'private_key_file'")
+	}
+	return nil
+}
+
+// login:: returns a new authenticated salesforce client for the connection config
+func login(ctx context.Context, config salesforceConfig) (*simpleforce.Client, error) {
+	apiVersion := simpleforce.DefaultAPIVersion
+	clientID := "steampipe"
+
+	if config.ClientId != nil {
+		clientID = *config.ClientId
+	}
+
+	if config.APIVersion != nil {
+		apiVersion = *config.APIVersion
+	}
+
+	if config.URL == nil {
+		plugin.Logger(ctx).Warn("salesforce.login", "'url' must be set in the connection configuration. Edit your connection configuration file and then restart Steampipe")
+		return nil, nil
+	}
+
+	if err := validateAuthConfig(config); err != nil {
+		plugin.Logger(ctx).Error("salesforce.login", "config_error", err)
+		return nil, err
+	}
+
+	// setup client
+	client := simpleforce.NewClient(*config.URL, clientID, apiVersion)
+	if client == nil {
+		plugin.Logger(ctx).Error("salesforce.login", "couldn't get salesforce client. Client setup error.")
+		return nil, fmt.Errorf("salesforce.login couldn't get salesforce client. Client setup error.")
+	}
+
+	authMode := getAuthMode(config)
+	plugin.Logger(ctx).Debug("salesforce.login", "auth_mode", authMode)
+
+	switch authMode {
+	case authModeJWTBearer:
+		if config.Username == nil {
+			plugin.Logger(ctx).Warn("salesforce.login", "'username' must be set in the connection configuration to use 'private_key_file'. Edit your connection configuration file and then restart Steampipe")
+			return nil, nil
+		}
+		token, err := loginJWTBearer(ctx, *config.URL, *config.ConsumerKey, *config.Username, *config.PrivateKeyFile)
+		if err != nil {
+			plugin.Logger(ctx).Error("salesforce.login", "jwt bearer login error", err)
+			return nil, fmt.Errorf("client login error %v", err)
+		}
+		client.SetSidLoc(token.AccessToken, token.InstanceURL)
+	case authModeClientCredentials:
+		token, err := loginClientCredentials(ctx, *config.URL, *config.ClientId, *config.ClientSecret)
+		if err != nil {
+			plugin.Logger(ctx).Error("salesforce.login", "client credentials login error", err)
+			return nil, fmt.Errorf("client login error %v", err)
+		}
+		client.SetSidLoc(token.AccessToken, token.InstanceURL)
+	default:
+		if config.Username == nil {
+			plugin.Logger(ctx).Warn("salesforce.login", "'username' must be set in the connection configuration. Edit your connection configuration file and then restart Steampipe")
+			return nil, nil
+		}
+
+		if config.Password == nil {
+			plugin.Logger(ctx).Warn("salesforce.login", "'password' must be set in the connection configuration. Edit your connection configuration file and then restart Steampipe")
+			return nil, nil
+		}
+
+		// The Salesforce security token is only required If the client's IP address is not added to the organization's list of trusted IPs
+		// https://help.salesforce.com/s/articleView?id=sf.security_networkaccess.htm&type=5
+		// https://migration.trujay.com/help/how-to-add-an-ip-address-to-whitelist-on-salesforce/
+		securityToken := ""
+		if config.Token != nil {
+			securityToken = *config.Token
+		}
+
+		// LoginPassword signs into salesforce using password. token is optional if trusted IP is configured.
+		// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/intro_understanding_username_password_oauth_flow.htm
+		// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api.meta/api/sforce_api_calls_login.htm
+		err := client.LoginPassword(*config.Username, *config.Password, securityToken)
+		if err != nil {
+			plugin.Logger(ctx).Error("salesforce.login", "client login error", err)
+			return nil, fmt.Errorf("client login error %v", err)
+		}
+	}
+
+	return client, nil
+}
+
+// loginJWTBearer:: exchanges a signed JWT assertion for an access token
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_jwt_flow.htm&type=5
+func loginJWTBearer(ctx context.Context, baseURL string, consumerKey string, username string, privateKeyFile string) (*oauthTokenResponse, error) {
+	keyData, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private key file %s: %v", privateKeyFile, err)
+	}
+	privateKey, err := parseRSAPrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key file %s: %v", privateKeyFile, err)
+	}
+
+	assertion, err := buildJWTAssertion(consumerKey, username, jwtAudience(baseURL), privateKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	return requestOAuthToken(ctx, baseURL, form)
+}
+
+// loginClientCredentials:: exchanges the connected app client id and secret for an access token
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_client_credentials_flow.htm&type=5
+func loginClientCredentials(ctx context.Context, baseURL string, clientID string, clientSecret string) (*oauthTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	return requestOAuthToken(ctx, baseURL, form)
+}
+
+// requestOAuthToken:: posts the form to the token endpoint of the given base URL and decodes the token response
+func requestOAuthToken(ctx context.Context, baseURL string, form url.Values) (*oauthTokenResponse, error) {
+	tokenURL := strings.TrimSuffix(baseURL, "/") + "/services/oauth2/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &oauthTokenResponse{}
+	if err = json.Unmarshal(body, token); err != nil {
+		return nil, fmt.Errorf("unable to decode token response (http code %d): %v", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token request failed (http code %d): %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" || token.InstanceURL == "" {
+		return nil, fmt.Errorf("token response is missing access_token or instance_url")
+	}
+
+	return token, nil
+}
+
+// jwtAudience:: returns the authorization server the JWT assertion is issued for.
+// Sandboxes authenticate against test.salesforce.com, everything else against login.salesforce.com.
+func jwtAudience(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err == nil && (parsed.Host == "test.salesforce.com" || strings.Contains(parsed.Host, ".sandbox.")) {
+		return "https://test.salesforce.com"
+	}
+	return "https://login.salesforce.com"
+}
+
+// buildJWTAssertion:: returns a RS256 signed JWT for the OAuth 2.0 JWT bearer flow
+func buildJWTAssertion(consumerKey string, username string, audience string, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": consumerKey,
+		"sub": username,
+		"aud": audience,
+		"exp": now.Add(jwtAssertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign jwt assertion: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey:: decodes a PEM encoded PKCS#1 or PKCS#8 RSA private key
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,149 @@
+package salesforce
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTokenServer returns a stand-in for the /services/oauth2/token endpoint that checks each request
+// and answers with an access token for its own URL
+func newTokenServer(t *testing.T, check func(r *http.Request) bool) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/services/oauth2/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil || !check(r) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"authentication failure"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "00Dxx0000000001!token",
+			"instance_url": server.URL,
+			"token_type":   "Bearer",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writePrivateKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "server.key")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+// verifyJWTAssertion checks the signature of the assertion and returns its claims
+func verifyJWTAssertion(t *testing.T, assertion string, publicKey *rsa.PublicKey) map[string]interface{} {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion has %d parts, want 3", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature); err != nil {
+		t.Fatalf("assertion signature: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestLoginJWTBearer(t *testing.T) {
+	key, keyFile := writePrivateKey(t)
+	server := newTokenServer(t, func(r *http.Request) bool {
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			return false
+		}
+		claims := verifyJWTAssertion(t, r.PostForm.Get("assertion"), &key.PublicKey)
+		return claims["iss"] == "consumer-key" && claims["sub"] == "admin@example.com" && claims["aud"] == "https://login.salesforce.com"
+	})
+
+	client, err := login(testContext(), salesforceConfig{
+		URL:            &server.URL,
+		Username:       stringPtr("admin@example.com"),
+		ConsumerKey:    stringPtr("consumer-key"),
+		PrivateKeyFile: &keyFile,
+	})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if client.GetSid() != "00Dxx0000000001!token" || client.GetLoc() != server.URL {
+		t.Errorf("client session = %q at %q, want the token response", client.GetSid(), client.GetLoc())
+	}
+}
+
+func TestLoginClientCredentials(t *testing.T) {
+	server := newTokenServer(t, func(r *http.Request) bool {
+		return r.PostForm.Get("grant_type") == "client_credentials" &&
+			r.PostForm.Get("client_id") == "client-id" &&
+			r.PostForm.Get("client_secret") == "client-secret"
+	})
+
+	client, err := login(testContext(), salesforceConfig{
+		URL:          &server.URL,
+		ClientId:     stringPtr("client-id"),
+		ClientSecret: stringPtr("client-secret"),
+	})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if client.GetSid() != "00Dxx0000000001!token" || client.GetLoc() != server.URL {
+		t.Errorf("client session = %q at %q, want the token response", client.GetSid(), client.GetLoc())
+	}
+
+	_, err = login(testContext(), salesforceConfig{
+		URL:          &server.URL,
+		ClientId:     stringPtr("client-id"),
+		ClientSecret: stringPtr("wrong-secret"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("login with a wrong secret: err = %v, want invalid_grant", err)
+	}
+}
+
+func TestLoginIncompleteJWTBearerConfig(t *testing.T) {
+	server := newTokenServer(t, func(r *http.Request) bool { return true })
+	tests := map[string]salesforceConfig{
+		"consumer_key without private_key_file": {URL: &server.URL, Username: stringPtr("admin@example.com"), Password: stringPtr("password"), ConsumerKey: stringPtr("consumer-key")},
+		"private_key_file without consumer_key": {URL: &server.URL, Username: stringPtr("admin@example.com"), Password: stringPtr("password"), PrivateKeyFile: stringPtr("server.key")},
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := login(testContext(), config)
+			if err == nil || client != nil {
+				t.Errorf("login = %v, %v, want a config error", client, err)
+			}
+		})
+	}
+}
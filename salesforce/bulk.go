@@ -0,0 +1,235 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Number of rows requested per Bulk API 2.0 result chunk
+const bulkQueryMaxRecords = 50000
+
+// Bounds of the interval used to poll Bulk API 2.0 query jobs
+const (
+	bulkQueryMinPollInterval = time.Second
+	bulkQueryMaxPollInterval = 10 * time.Second
+)
+
+// bulkQueryJob is the subset of a Bulk API 2.0 query job used by the plugin
+type bulkQueryJob struct {
+	ID           string `json:"id"`
+	State        string `json:"state"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// useBulkQuery:: checks if the list call for the object should go through Bulk API 2.0, which is the case if
+// the object is listed in bulk_api_objects, or if the query matches at least bulk_api_threshold rows.
+// Checking the threshold costs a COUNT() query, which is skipped if the SQL limit is below the threshold.
+// Bulk API 2.0 doesn't support compound fields or subqueries, so queries selecting address, location or child
+// relationship columns use the REST API.
+func useBulkQuery(ctx context.Context, d *plugin.QueryData, tableName string, config salesforceConfig, queryColumns []*plugin.Column, salesforceCols map[string]string, condition string) bool {
+	for _, column := range queryColumns {
+		switch salesforceCols[column.Name] {
+		case "address", "location", childRelationshipFieldType:
+			return false
+		}
+	}
+
+	if config.BulkAPIObjects != nil {
+		for _, name := range *config.BulkAPIObjects {
+			if strings.EqualFold(name, tableName) {
+				return true
+			}
+		}
+	}
+
+	if config.BulkAPIThreshold == nil || *config.BulkAPIThreshold <= 0 {
+		return false
+	}
+	if d.QueryContext.Limit != nil && *d.QueryContext.Limit < int64(*config.BulkAPIThreshold) {
+		return false
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT() FROM %s", tableName)
+	if condition != "" {
+		countQuery = fmt.Sprintf("%s WHERE %s", countQuery, condition)
+	}
+	result, err := querySalesforce(ctx, d, countQuery)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.useBulkQuery", "count_query_error", err)
+		return false
+	}
+	plugin.Logger(ctx).Debug("salesforce.useBulkQuery", "table_name", tableName, "total_size", result.TotalSize)
+	return result.TotalSize >= *config.BulkAPIThreshold
+}
+
+// listByBulkQuery:: runs the SOQL query as a Bulk API 2.0 query job and calls streamRow for every row
+// of the CSV results, converted to the same shape as REST query records.
+// If queryAll is set, the job also returns deleted and archived records.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/queries.htm
+func listByBulkQuery(ctx context.Context, d *plugin.QueryData, query string, queryAll bool, columnsMap map[string]*plugin.Column, streamRow func(row map[string]interface{}) bool) error {
+	jobsPath := fmt.Sprintf("services/data/v%s/jobs/query", getAPIVersion(GetConfig(d.Connection)))
+
+	operation := "query"
+	if queryAll {
+		operation = "queryAll"
+	}
+	request, err := json.Marshal(map[string]string{"operation": operation, "query": query})
+	if err != nil {
+		return err
+	}
+	data, _, err := restRequest(ctx, d, http.MethodPost, jobsPath, request, "application/json")
+	if err != nil {
+		return err
+	}
+	job := &bulkQueryJob{}
+	if err = json.Unmarshal(data, job); err != nil {
+		return err
+	}
+	plugin.Logger(ctx).Debug("salesforce.listByBulkQuery", "job_id", job.ID, "query", query)
+
+	jobPath := fmt.Sprintf("%s/%s", jobsPath, job.ID)
+	completed := false
+	defer func() {
+		// Stop the job if the query was cancelled or failed before it completed
+		if !completed {
+			abortBulkQueryJob(ctx, d, jobPath)
+		}
+	}()
+
+	pollInterval := bulkQueryMinPollInterval
+	for job.State != "JobComplete" {
+		switch job.State {
+		case "Failed", "Aborted":
+			completed = true
+			return fmt.Errorf("bulk query job %s %s: %s", job.ID, strings.ToLower(job.State), job.ErrorMessage)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+		if pollInterval *= 2; pollInterval > bulkQueryMaxPollInterval {
+			pollInterval = bulkQueryMaxPollInterval
+		}
+
+		data, _, err = restRequest(ctx, d, http.MethodGet, jobPath, nil, "")
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, job); err != nil {
+			return err
+		}
+	}
+	completed = true
+
+	locator := ""
+	for {
+		resultsPath := fmt.Sprintf("%s/results?maxRecords=%d", jobPath, bulkQueryMaxRecords)
+		if locator != "" {
+			resultsPath = fmt.Sprintf("%s&locator=%s", resultsPath, locator)
+		}
+		data, header, err := restRequest(ctx, d, http.MethodGet, resultsPath, nil, "")
+		if err != nil {
+			return err
+		}
+
+		rows, err := decodeBulkQueryResults(data, columnsMap)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if !streamRow(row) {
+				return nil
+			}
+		}
+
+		// Sforce-Locator is "null" once the last chunk has been returned
+		locator = header.Get("Sforce-Locator")
+		if locator == "" || locator == "null" {
+			return nil
+		}
+	}
+}
+
+// abortBulkQueryJob:: aborts an unfinished Bulk API 2.0 query job
+func abortBulkQueryJob(ctx context.Context, d *plugin.QueryData, jobPath string) {
+	// The query context may already be cancelled at this point
+	abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	// Aborting stops the job from using more resources, so it isn't subject to min_api_remaining_percent
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	_, _, _, err := connectionRequest(abortCtx, d.ConnectionCache, d.Connection, http.MethodPatch, jobPath, []byte(`{"state":"Aborted"}`), header)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.abortBulkQueryJob", "job_path", jobPath, "abort_error", err)
+	}
+}
+
+// decodeBulkQueryResults:: converts a CSV result chunk into records keyed by Salesforce field name.
+// CSV values are typed from the column types, so rows match what the REST query endpoint returns.
+func decodeBulkQueryResults(data []byte, columnsMap map[string]*plugin.Column) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]interface{}{}
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(header))
+		for i, fieldName := range header {
+			row[fieldName] = convertBulkQueryValue(values[i], columnsMap[fieldName])
+		}
+		rows = append(rows, row)
+	}
+}
+
+// convertBulkQueryValue:: converts a CSV value to the type the REST API would return for the column.
+// Bulk API 2.0 writes null values as empty strings.
+func convertBulkQueryValue(value string, column *plugin.Column) interface{} {
+	if value == "" {
+		return nil
+	}
+	if column == nil {
+		return value
+	}
+
+	switch column.Type {
+	case proto.ColumnType_BOOL:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case proto.ColumnType_INT, proto.ColumnType_DOUBLE:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case proto.ColumnType_JSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+package salesforce
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func TestUseBulkQueryThreshold(t *testing.T) {
+	var mu sync.Mutex
+	var countQueries []string
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		countQueries = append(countQueries, r.URL.Query().Get("q"))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"totalSize":5000,"done":true,"records":[]}`))
+	})
+	queryColumns := []*plugin.Column{{Name: "name", Type: proto.ColumnType_STRING}}
+	salesforceCols := map[string]string{"name": "string"}
+	limit := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name      string
+		threshold int
+		limit     *int64
+		wantBulk  bool
+		wantCount bool
+	}{
+		{"no limit above threshold", 1000, nil, true, true},
+		{"no limit below threshold", 10000, nil, false, true},
+		{"limit below threshold", 1000, limit(10), false, false},
+		{"limit at threshold", 1000, limit(1000), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			countQueries = nil
+			config := salesforceConfig{BulkAPIThreshold: &tt.threshold}
+			d := f.queryData("test", config)
+			d.QueryContext.Limit = tt.limit
+
+			if got := useBulkQuery(testContext(), d, "Account", GetConfig(d.Connection), queryColumns, salesforceCols, "Name != 'Acme'"); got != tt.wantBulk {
+				t.Errorf("useBulkQuery = %v, want %v", got, tt.wantBulk)
+			}
+			if tt.wantCount != (len(countQueries) == 1) {
+				t.Errorf("count queries = %q, want count query %v", countQueries, tt.wantCount)
+			}
+			if len(countQueries) == 1 && countQueries[0] != "SELECT COUNT() FROM Account WHERE Name != 'Acme'" {
+				t.Errorf("count query = %q", countQueries[0])
+			}
+		})
+	}
+
+	// Queries selecting compound fields never use Bulk API 2.0, so they are not counted
+	countQueries = nil
+	d := f.queryData("test", salesforceConfig{BulkAPIThreshold: intPtr(1)})
+	if useBulkQuery(testContext(), d, "Account", GetConfig(d.Connection), []*plugin.Column{{Name: "billing_address"}}, map[string]string{"billing_address": "address"}, "") {
+		t.Error("useBulkQuery with an address column = true, want false")
+	}
+	if len(countQueries) != 0 {
+		t.Errorf("count queries = %q, want none", countQueries)
+	}
+}
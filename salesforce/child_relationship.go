@@ -0,0 +1,269 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Field type of child relationship columns in the salesforceCols maps
+const childRelationshipFieldType = "childRelationship"
+
+// childRelationship is a child relationship column of an object, e.g. the contacts column of Account.
+// It is the transform param of the column, so each connection's tables keep their own subqueries.
+type childRelationship struct {
+	RelationshipName string
+	Subquery         string
+}
+
+// getChildRelationship:: returns the child relationship of a child relationship column, which is its transform param
+func getChildRelationship(column *plugin.Column) (childRelationship, bool) {
+	if column.Transform == nil || len(column.Transform.Transforms) == 0 {
+		return childRelationship{}, false
+	}
+	relationship, ok := column.Transform.Transforms[0].Param.(childRelationship)
+	return relationship, ok
+}
+
+// getChildRelationshipNames:: returns the relationship names of the child relationship columns in columns
+func getChildRelationshipNames(columns []*plugin.Column) []string {
+	names := []string{}
+	for _, column := range columns {
+		if relationship, ok := getChildRelationship(column); ok {
+			names = append(names, relationship.RelationshipName)
+		}
+	}
+	return names
+}
+
+// getChildRelationshipColumnName:: returns the column name for a child relationship, e.g. Contacts => contacts
+// and Custom_Children__r => custom_children__r. With api_native naming the relationship name is used as is.
+func getChildRelationshipColumnName(config salesforceConfig, relationshipName string) string {
+	if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+		return relationshipName
+	}
+	if strings.HasSuffix(relationshipName, "__r") {
+		return strings.ToLower(relationshipName)
+	}
+	return strcase.ToSnake(relationshipName)
+}
+
+// getChildRelationshipConfig:: returns the child relationships configured per object in child_relationship_config
+func getChildRelationshipConfig(ctx context.Context, config salesforceConfig) map[string][]string {
+	childRelationships := map[string][]string{}
+	if config.ChildRelationshipConfig == nil {
+		return childRelationships
+	}
+
+	childRelationshipConfigs := []UserDefinedDynamicColumnConfig{}
+	err := json.Unmarshal([]byte(*config.ChildRelationshipConfig), &childRelationshipConfigs)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.getChildRelationshipConfig", "invalid child_relationship_config JSON", err)
+		return childRelationships
+	}
+	for _, childRelationshipConfig := range childRelationshipConfigs {
+		childRelationships[childRelationshipConfig.Name] = append(childRelationships[childRelationshipConfig.Name], childRelationshipConfig.Columns...)
+	}
+	return childRelationships
+}
+
+// parseChildRelationshipEntry:: splits a child_relationship_config entry, e.g. "Contacts(Id, Name, Email)",
+// into the relationship name and the child fields. Without a field list all child fields are returned.
+func parseChildRelationshipEntry(entry string) (string, []string) {
+	name, fieldList, found := strings.Cut(entry, "(")
+	name = strings.TrimSpace(name)
+	if !found {
+		return name, nil
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(strings.TrimSuffix(strings.TrimSpace(fieldList), ")"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return name, fields
+}
+
+// childRelationshipColumns:: returns JSON columns for the configured child relationships of an object. Selecting
+// one of them adds a subquery on the relationship, e.g. (SELECT Id, Name FROM Contacts), to the SOQL query.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_relationships_query_using.htm
+func childRelationshipColumns(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, objectName string, sObjectMeta simpleforce.SObjectMeta, entries []string, salesforceCols map[string]string) ([]*plugin.Column, map[string]string) {
+	cols := []*plugin.Column{}
+	childCols := map[string]string{}
+	if len(entries) == 0 {
+		return cols, childCols
+	}
+
+	describedRelationships := []map[string]interface{}{}
+	relationshipsAsByte, err := json.Marshal(sObjectMeta["childRelationships"])
+	if err == nil {
+		err = json.Unmarshal(relationshipsAsByte, &describedRelationships)
+	}
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.childRelationshipColumns", "object_name", objectName, "child relationships decoding error", err)
+		return cols, childCols
+	}
+
+	for _, entry := range entries {
+		relationshipName, fields := parseChildRelationshipEntry(entry)
+
+		var childObject string
+		for _, relationship := range describedRelationships {
+			if name, ok := relationship["relationshipName"].(string); ok && strings.EqualFold(name, relationshipName) {
+				relationshipName = name
+				childObject, _ = relationship["childSObject"].(string)
+				break
+			}
+		}
+		if childObject == "" {
+			plugin.Logger(ctx).Warn("salesforce.childRelationshipColumns", "unable to resolve child relationship", entry)
+			continue
+		}
+
+		columnName := getChildRelationshipColumnName(config, relationshipName)
+		if _, ok := salesforceCols[columnName]; ok {
+			plugin.Logger(ctx).Warn("salesforce.childRelationshipColumns", "child relationship column conflicts with a field", columnName)
+			continue
+		}
+
+		if len(fields) == 0 {
+			fields = getChildRelationshipFields(ctx, td, config, childObject)
+			if len(fields) == 0 {
+				plugin.Logger(ctx).Warn("salesforce.childRelationshipColumns", "unable to describe child object", childObject)
+				continue
+			}
+		}
+
+		relationship := childRelationship{
+			RelationshipName: relationshipName,
+			Subquery:         fmt.Sprintf("(SELECT %s FROM %s)", strings.Join(fields, ", "), relationshipName),
+		}
+		cols = append(cols, &plugin.Column{
+			Name:        columnName,
+			Type:        proto.ColumnType_JSON,
+			Description: fmt.Sprintf("%s records related through the %s relationship.", childObject, relationshipName),
+			Transform:   transform.FromP(getChildRelationshipRecords, relationship),
+		})
+		childCols[columnName] = childRelationshipFieldType
+	}
+	return cols, childCols
+}
+
+// getChildRelationshipFields:: returns the fields of the child object that can be used in a subquery.
+// Compound field components are skipped like in the table columns, and base64 fields can't be queried
+// for more than one record at a time.
+func getChildRelationshipFields(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, childObject string) []string {
+	sObjectMeta := describeSObject(ctx, td, config, childObject)
+	if sObjectMeta == nil {
+		return nil
+	}
+
+	fields := []string{}
+	for _, field := range getDescribeFields(ctx, *sObjectMeta) {
+		name, ok := field["name"].(string)
+		if !ok || field["soapType"] == nil || field["type"] == "base64" {
+			continue
+		}
+		if compoundFieldName, ok := field["compoundFieldName"].(string); ok && compoundFieldName != name {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// fetchChildRelationshipRecords:: replaces the nested query results of the child relationships in the record with
+// arrays of the child records. Subqueries returning more children than fit in a batch are paged through their
+// nextRecordsUrl.
+func fetchChildRelationshipRecords(ctx context.Context, d *plugin.QueryData, record map[string]interface{}, relationshipNames []string) error {
+	for _, relationshipName := range relationshipNames {
+		children := []map[string]interface{}{}
+
+		// Salesforce returns null instead of an empty query result if there are no child records
+		if nested, ok := record[relationshipName].(map[string]interface{}); ok {
+			result := &simpleforce.QueryResult{}
+			if err := decodeQueryResult(ctx, nested, result); err != nil {
+				return err
+			}
+			for {
+				page := []map[string]interface{}{}
+				if err := decodeQueryResult(ctx, result.Records, &page); err != nil {
+					return err
+				}
+				children = append(children, page...)
+
+				if result.Done || result.NextRecordsURL == "" {
+					break
+				}
+				var err error
+				result, err = querySalesforce(ctx, d, result.NextRecordsURL)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, child := range children {
+			delete(child, "attributes")
+		}
+		record[relationshipName] = children
+	}
+	return nil
+}
+
+// addChildRelationshipRecords:: adds the child relationships of a single record to object, which is used when the
+// record was fetched through the sObject rows resource as that doesn't return child records
+func addChildRelationshipRecords(ctx context.Context, d *plugin.QueryData, objectName string, id string, columns []*plugin.Column, object map[string]interface{}) error {
+	subqueries := []string{}
+	relationshipNames := []string{}
+	for _, column := range columns {
+		if relationship, ok := getChildRelationship(column); ok {
+			subqueries = append(subqueries, relationship.Subquery)
+			relationshipNames = append(relationshipNames, relationship.RelationshipName)
+		}
+	}
+	if len(subqueries) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE Id = %s", strings.Join(subqueries, ", "), objectName, soqlStringLiteral(id))
+	result, err := querySalesforce(ctx, d, query)
+	if err != nil {
+		return err
+	}
+	records := []map[string]interface{}{}
+	if err = decodeQueryResult(ctx, result.Records, &records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err = fetchChildRelationshipRecords(ctx, d, records[0], relationshipNames); err != nil {
+		return err
+	}
+	for _, relationshipName := range relationshipNames {
+		object[relationshipName] = records[0][relationshipName]
+	}
+	return nil
+}
+
+//// TRANSFORM FUNCTION
+
+// getChildRelationshipRecords:: returns the child records of a child relationship column from a record
+func getChildRelationshipRecords(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	relationship := d.Param.(childRelationship)
+	record, ok := d.HydrateItem.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	return record[relationship.RelationshipName], nil
+}
@@ -0,0 +1,80 @@
+package salesforce
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func TestChildRelationshipColumnsArePerConnection(t *testing.T) {
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	})
+	accountDescribe := simpleforce.SObjectMeta{
+		"name":               "Account",
+		"childRelationships": []interface{}{map[string]interface{}{"relationshipName": "Contacts", "childSObject": "Contact"}},
+	}
+	ctx := testContext()
+
+	// Two connections configure different fields for the contacts column of Account
+	tdA := f.tableMapData("child_a", salesforceConfig{})
+	colsA, _ := childRelationshipColumns(ctx, tdA, GetConfig(tdA.Connection), "Account", accountDescribe, []string{"Contacts(Id, Name)"}, map[string]string{})
+	tdB := f.tableMapData("child_b", salesforceConfig{})
+	colsB, _ := childRelationshipColumns(ctx, tdB, GetConfig(tdB.Connection), "Account", accountDescribe, []string{"Contacts(Id, Email)"}, map[string]string{})
+	if len(colsA) != 1 || len(colsB) != 1 || colsA[0].Name != "contacts" || colsB[0].Name != "contacts" {
+		t.Fatalf("child relationship columns = %v and %v, want contacts", colsA, colsB)
+	}
+
+	if got := generateQuery(colsA, "Account"); got != "SELECT (SELECT Id, Name FROM Contacts) FROM Account" {
+		t.Errorf("generateQuery of the first connection = %q", got)
+	}
+	if got := generateQuery(colsB, "Account"); got != "SELECT (SELECT Id, Email FROM Contacts) FROM Account" {
+		t.Errorf("generateQuery of the second connection = %q", got)
+	}
+	if got := getChildRelationshipNames(colsA); len(got) != 1 || got[0] != "Contacts" {
+		t.Errorf("getChildRelationshipNames = %v, want [Contacts]", got)
+	}
+}
+
+func TestCustomChildRelationshipColumnFromCachedRecord(t *testing.T) {
+	ctx := testContext()
+	d := newFakeOrg(t, "00D000000000003").queryData("child_custom", salesforceConfig{})
+	accountDescribe := simpleforce.SObjectMeta{
+		"name":               "Account",
+		"childRelationships": []interface{}{map[string]interface{}{"relationshipName": "Custom_Children__r", "childSObject": "Custom_Child__c"}},
+	}
+	cols, _ := childRelationshipColumns(ctx, nil, GetConfig(d.Connection), "Account", accountDescribe, []string{"custom_children__r(Id, Name)"}, map[string]string{})
+	if len(cols) != 1 || cols[0].Name != "custom_children__r" {
+		t.Fatalf("child relationship columns = %v, want custom_children__r", cols)
+	}
+	column := cols[0]
+	if got := getColumnFieldName(column); got != "Custom_Children__r" {
+		t.Fatalf("getColumnFieldName = %q, want Custom_Children__r", got)
+	}
+
+	// The record was cached by a list query, with the child records under the relationship name
+	setForeignKeyGraph(d.Connection.Name, []cache.KeyStruct{{Name: "Account", Pk: "Id"}})
+	recordCache := getRecordCache(ctx, d)
+	children := []interface{}{map[string]interface{}{"Id": "a00000000000001AAA", "Name": "First child"}}
+	recordCache.AddIdsToForeignTableCache(ctx, "Account", map[string]interface{}{"Id": "001000000000001AAA", "Custom_Children__r": children})
+
+	columnsMap := map[string]*plugin.Column{"Id": {Name: "id"}, getColumnFieldName(column): column}
+	record, err := recordCache.GetRecordByIdAndBuildCache(ctx, d, nil, "Account", "001000000000001AAA", columnsMap)
+	if err != nil {
+		t.Fatalf("GetRecordByIdAndBuildCache: %v", err)
+	}
+	if record == nil {
+		t.Fatal("record isn't served from the cache")
+	}
+	value, err := getChildRelationshipRecords(ctx, &transform.TransformData{Param: column.Transform.Transforms[0].Param, HydrateItem: record})
+	if err != nil {
+		t.Fatalf("getChildRelationshipRecords: %v", err)
+	}
+	if got, ok := value.([]interface{}); !ok || len(got) != 1 {
+		t.Errorf("custom_children__r = %v, want the cached child records", value)
+	}
+}
@@ -0,0 +1,222 @@
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/connection"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Key of the authenticated client in the connection cache
+const clientCacheKey = "simpleforce"
+
+// Locks serializing re-authentication per connection name, so concurrent hydrates hitting an expired
+// session only log in once without blocking the other connections
+var sessionRefreshLocks sync.Map
+
+// getSessionRefreshLock:: returns the lock serializing the re-authentication of the connection
+func getSessionRefreshLock(connectionName string) *sync.Mutex {
+	lock, _ := sessionRefreshLocks.LoadOrStore(connectionName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// isSessionExpiredError:: checks if the error was caused by an expired or revoked session
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/errorcodes.htm
+func isSessionExpiredError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, simpleforce.ErrAuthentication) {
+		return true
+	}
+	return strings.Contains(err.Error(), "INVALID_SESSION_ID")
+}
+
+// refreshConnection:: replaces the cached client with a newly authenticated one.
+// If another goroutine already replaced staleClient, the cached client is returned as is.
+func refreshConnection(ctx context.Context, cc *connection.ConnectionCache, c *plugin.Connection, staleClient *simpleforce.Client) (*simpleforce.Client, error) {
+	lock := getSessionRefreshLock(c.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if cc != nil {
+		if cachedData, ok := cc.Get(ctx, clientCacheKey); ok {
+			if cachedClient := cachedData.(*simpleforce.Client); cachedClient != staleClient {
+				return cachedClient, nil
+			}
+		}
+		cc.Delete(ctx, clientCacheKey)
+	}
+
+	plugin.Logger(ctx).Info("salesforce.refreshConnection", "session expired, logging in again")
+	return connectRaw(ctx, cc, c)
+}
+
+// withConnection:: calls fn with the connection's client. If the call fails because the session expired,
+// the client is re-authenticated and fn is called once more.
+func withConnection(ctx context.Context, cc *connection.ConnectionCache, c *plugin.Connection, fn func(client *simpleforce.Client) error) error {
+	client, err := connectRaw(ctx, cc, c)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return fmt.Errorf("client_not_found, unable to query connection %s because of invalid steampipe salesforce configuration", c.Name)
+	}
+
+	err = fn(client)
+	if !isSessionExpiredError(err) {
+		return err
+	}
+
+	plugin.Logger(ctx).Warn("salesforce.withConnection", "connection", c.Name, "session_error", err)
+	client, err = refreshConnection(ctx, cc, c, client)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return fmt.Errorf("client_not_found, unable to query connection %s because of invalid steampipe salesforce configuration", c.Name)
+	}
+	return fn(client)
+}
+
+// querySalesforce:: runs a SOQL query, or fetches the next page if query is a nextRecordsUrl
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_query.htm
+func querySalesforce(ctx context.Context, d *plugin.QueryData, query string) (*simpleforce.QueryResult, error) {
+	path := query
+	if !strings.HasPrefix(query, "/services/data") {
+		path = fmt.Sprintf("services/data/v%s/query?q=%s", getAPIVersion(GetConfig(d.Connection)), url.QueryEscape(query))
+	}
+	data, _, err := restRequest(ctx, d, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &simpleforce.QueryResult{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// queryAllSalesforce:: same as querySalesforce, but uses the queryAll resource so the results also
+// include deleted records in the Recycle Bin and archived Task and Event records
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_queryall.htm
+func queryAllSalesforce(ctx context.Context, d *plugin.QueryData, query string) (*simpleforce.QueryResult, error) {
+	// Pages of a queryAll result are fetched like any other nextRecordsUrl
+	if strings.HasPrefix(query, "/services/data") {
+		return querySalesforce(ctx, d, query)
+	}
+
+	path := fmt.Sprintf("services/data/v%s/queryAll?q=%s", getAPIVersion(GetConfig(d.Connection)), url.QueryEscape(query))
+	data, _, err := restRequest(ctx, d, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &simpleforce.QueryResult{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getSObject:: fetches a single record by id through the sObject rows resource.
+// Unlike simpleforce's SObject.Get, request errors are returned to the caller.
+func getSObject(ctx context.Context, d *plugin.QueryData, objectName string, id string) (map[string]interface{}, error) {
+	path := fmt.Sprintf("services/data/v%s/sobjects/%s/%s", getAPIVersion(GetConfig(d.Connection)), objectName, id)
+	data, _, err := restRequest(ctx, d, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	object := map[string]interface{}{}
+	if err = json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// getAPIVersion:: returns the configured API version without any "v" prefix
+func getAPIVersion(config salesforceConfig) string {
+	if config.APIVersion != nil {
+		return strings.TrimPrefix(*config.APIVersion, "v")
+	}
+	return simpleforce.DefaultAPIVersion
+}
+
+// restRequest:: sends a request to a REST resource relative to the instance URL and returns the
+// response body and headers. Unlike simpleforce's ApexREST, headers are available to the caller.
+// Requests fail without being sent if the daily API requests remaining are below min_api_remaining_percent.
+func restRequest(ctx context.Context, d *plugin.QueryData, method string, path string, body []byte, contentType string) ([]byte, http.Header, error) {
+	if err := checkAPIBudget(ctx, d); err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	_, respBody, respHeader, err := connectionRequest(ctx, d.ConnectionCache, d.Connection, method, path, body, header)
+	return respBody, respHeader, err
+}
+
+// connectionRequest:: same as restRequest for requests made outside a hydrate, returning the status code as well.
+// Responses other than 2xx and 304 Not Modified, e.g. to a request with If-Modified-Since, are returned as errors.
+// GET requests failing with a transient error are sent again with backoff. Other methods aren't retried, as
+// Salesforce may have processed the request, e.g. created a bulk query job, before the error was returned.
+func connectionRequest(ctx context.Context, cc *connection.ConnectionCache, c *plugin.Connection, method string, path string, body []byte, header http.Header) (int, []byte, http.Header, error) {
+	var statusCode int
+	var respBody []byte
+	var respHeader http.Header
+	send := func() error {
+		return withConnection(ctx, cc, c, func(client *simpleforce.Client) error {
+			var reqBody io.Reader
+			if body != nil {
+				reqBody = bytes.NewReader(body)
+			}
+			req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(client.GetLoc(), "/")+"/"+strings.TrimPrefix(path, "/"), reqBody)
+			if err != nil {
+				return err
+			}
+			for name, values := range header {
+				req.Header[name] = values
+			}
+			req.Header.Set("Authorization", "Bearer "+client.GetSid())
+
+			resp, err := getHTTPClient(c.Name).Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			if (resp.StatusCode < 200 || resp.StatusCode > 299) && resp.StatusCode != http.StatusNotModified {
+				return parseResponseError(resp.StatusCode, data)
+			}
+			statusCode = resp.StatusCode
+			respBody = data
+			respHeader = resp.Header
+			return nil
+		})
+	}
+
+	var err error
+	if method == http.MethodGet {
+		err = withRetry(ctx, send)
+	} else {
+		err = send()
+	}
+	return statusCode, respBody, respHeader, err
+}
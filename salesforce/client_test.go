@@ -0,0 +1,238 @@
+package salesforce
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestRequestRefreshesExpiredSession(t *testing.T) {
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	d := f.queryData("test", salesforceConfig{})
+	ctx := testContext()
+
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, ""); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	f.expireSessions()
+
+	// Concurrent requests hitting the expired session share a single login
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("request after the session expired: %v", err)
+		}
+	}
+	if logins := f.loginCount(); logins != 2 {
+		t.Errorf("logins = %d, want 2", logins)
+	}
+}
+
+func TestSessionRefreshIsPerConnection(t *testing.T) {
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	d := f.queryData("refresh_b", salesforceConfig{})
+	ctx := testContext()
+	if _, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, ""); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	f.expireSessions()
+
+	// A refresh of another connection in progress doesn't block this one
+	lock := getSessionRefreshLock("refresh_a")
+	lock.Lock()
+	defer lock.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := restRequest(ctx, d, http.MethodGet, "services/data/v58.0/limits", nil, "")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("request after the session expired: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("refreshing the session waited for the refresh of another connection")
+	}
+}
+
+func TestDescribeSObjectRefreshesExpiredSession(t *testing.T) {
+	t.Setenv("STEAMPIPE_INSTALL_DIR", t.TempDir())
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v58.0/sobjects/Account/describe" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`[{"message":"The requested resource does not exist","errorCode":"NOT_FOUND"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"Account","fields":[{"name":"Id","type":"id"}]}`))
+	})
+	td := f.tableMapData("test", salesforceConfig{APIVersion: stringPtr("58.0")})
+	ctx := testContext()
+
+	if _, err := connectRaw(ctx, td.ConnectionCache, td.Connection); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	f.expireSessions()
+
+	meta := describeSObject(ctx, td, GetConfig(td.Connection), "Account")
+	if meta == nil || (*meta)["name"] != "Account" {
+		t.Fatalf("describeSObject = %v, want the Account describe", meta)
+	}
+	if logins := f.loginCount(); logins != 2 {
+		t.Errorf("logins = %d, want 2", logins)
+	}
+}
+
+func TestQuerySalesforceRetriesServiceUnavailable(t *testing.T) {
+	var requests int32
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v58.0/query" || r.URL.Query().Get("q") != "SELECT Id FROM Account" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// The first attempt hits a maintenance page instead of a Salesforce error
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<html><body>Down for maintenance</body></html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"001000000000001"}]}`))
+	})
+	d := f.queryData("retry", salesforceConfig{APIVersion: stringPtr("58.0")})
+
+	result, err := querySalesforce(testContext(), d, "SELECT Id FROM Account")
+	if err != nil {
+		t.Fatalf("querySalesforce: %v", err)
+	}
+	if result.TotalSize != 1 || len(result.Records) != 1 {
+		t.Errorf("result = %+v, want a single record", result)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestQuerySalesforceFetchesNextRecordsURL(t *testing.T) {
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v58.0/query/01g000000000001-2000" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"totalSize":2001,"done":true,"records":[{"Id":"001000000000002"}]}`))
+	})
+	d := f.queryData("next_page", salesforceConfig{APIVersion: stringPtr("58.0")})
+
+	result, err := querySalesforce(testContext(), d, "/services/data/v58.0/query/01g000000000001-2000")
+	if err != nil {
+		t.Fatalf("querySalesforce: %v", err)
+	}
+	if !result.Done || len(result.Records) != 1 {
+		t.Errorf("result = %+v, want the last page", result)
+	}
+}
+
+func TestRestRequestErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		status       int
+		body         string
+		wantRequests int32
+		wantErr      string
+	}{
+		{
+			name:         "empty error array",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			body:         `[]`,
+			wantRequests: 1,
+			wantErr:      "http code: 400 ",
+		},
+		{
+			name:         "salesforce error",
+			method:       http.MethodGet,
+			status:       http.StatusNotFound,
+			body:         `[{"message":"The requested resource does not exist","errorCode":"NOT_FOUND"}]`,
+			wantRequests: 1,
+			wantErr:      "Error Code: NOT_FOUND",
+		},
+		{
+			name:         "daily request limit is not retried",
+			method:       http.MethodGet,
+			status:       http.StatusForbidden,
+			body:         `[{"message":"TotalRequests Limit exceeded.","errorCode":"REQUEST_LIMIT_EXCEEDED"}]`,
+			wantRequests: 1,
+			wantErr:      "Error Code: REQUEST_LIMIT_EXCEEDED",
+		},
+		{
+			name:         "post is not retried",
+			method:       http.MethodPost,
+			status:       http.StatusServiceUnavailable,
+			body:         `<html><body>Down for maintenance</body></html>`,
+			wantRequests: 1,
+			wantErr:      "http code: 503 ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+			d := f.queryData("errors", salesforceConfig{})
+
+			_, _, err := restRequest(testContext(), d, tt.method, "services/data/v58.0/jobs/query", []byte(`{}`), "application/json")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if n := atomic.LoadInt32(&requests); n != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"concurrent request limit", http.StatusForbidden, `[{"message":"ConcurrentPerOrgLongTxn Limit exceeded.","errorCode":"REQUEST_LIMIT_EXCEEDED"}]`, true},
+		{"per second request limit", http.StatusForbidden, `[{"message":"Too many requests per second.","errorCode":"REQUEST_LIMIT_EXCEEDED"}]`, true},
+		{"daily request limit", http.StatusForbidden, `[{"message":"TotalRequests Limit exceeded.","errorCode":"REQUEST_LIMIT_EXCEEDED"}]`, false},
+		{"server unavailable", http.StatusServiceUnavailable, `[{"message":"Server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`, true},
+		{"maintenance page", http.StatusServiceUnavailable, `<html><body>Down for maintenance</body></html>`, true},
+		{"not found", http.StatusNotFound, `[{"message":"The requested resource does not exist","errorCode":"NOT_FOUND"}]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseResponseError(tt.status, []byte(tt.body))
+			if got := isRetryableError(err); got != tt.want {
+				t.Errorf("isRetryableError(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,18 +18,31 @@ type salesforceConfig struct {
 	Password                       *string               `cty:"password"`
 	Token                          *string               `cty:"token"`
 	ClientId                       *string               `cty:"client_id"`
+	ClientSecret                   *string               `cty:"client_secret"`
+	ConsumerKey                    *string               `cty:"consumer_key"`
+	PrivateKeyFile                 *string               `cty:"private_key_file"`
 	APIVersion                     *string               `cty:"api_version"`
 	Objects                        *[]string             `cty:"objects"`
 	NamingConvention               *NamingConventionEnum `cty:"naming_convention"`
 	UserDefinedDynamicColumnConfig *string               `cty:"user_defined_dynamic_column_config"`
 	ResultSize                     *int                  `cty:"result_size"`
 	ShowResultSizeError            *bool                 `cty:"show_result_size_error"`
-	CaseSenistiveSearch            *bool                 `cty:"case_sensitive_search"`
+	DescribeCacheTTL               *int                  `cty:"describe_cache_ttl"`
+	BulkAPIObjects                 *[]string             `cty:"bulk_api_objects"`
+	BulkAPIThreshold               *int                  `cty:"bulk_api_threshold"`
+	IncludeDeleted                 *bool                 `cty:"include_deleted"`
+	RelationshipColumnConfig       *string               `cty:"relationship_column_config"`
+	ChildRelationshipConfig        *string               `cty:"child_relationship_config"`
+	AllowWrites                    *bool                 `cty:"allow_writes"`
+	MinAPIRemainingPercent         *int                  `cty:"min_api_remaining_percent"`
+	PrefetchObjects                *[]string             `cty:"prefetch_objects"`
+	CacheMaxRecordsPerObject       *int                  `cty:"cache_max_records_per_object"`
+	CacheMemoryBudgetMB            *int                  `cty:"cache_memory_budget_mb"`
 }
 
 type UserDefinedDynamicColumnConfig struct {
-	Name    string   `json:name`
-	Columns []string `json:columns`
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
 }
 
 var ConfigSchema = map[string]*schema.Attribute{
@@ -51,6 +64,15 @@ var ConfigSchema = map[string]*schema.Attribute{
 	"client_id": {
 		Type: schema.TypeString,
 	},
+	"client_secret": {
+		Type: schema.TypeString,
+	},
+	"consumer_key": {
+		Type: schema.TypeString,
+	},
+	"private_key_file": {
+		Type: schema.TypeString,
+	},
 	"api_version": {
 		Type: schema.TypeString,
 	},
@@ -69,6 +91,45 @@ var ConfigSchema = map[string]*schema.Attribute{
 	"show_result_size_error": {
 		Type: schema.TypeBool,
 	},
+	"describe_cache_ttl": {
+		Type: schema.TypeInt,
+	},
+	"bulk_api_objects": {
+		Type: schema.TypeList,
+		Elem: &schema.Attribute{
+			Type: schema.TypeString,
+		},
+	},
+	"bulk_api_threshold": {
+		Type: schema.TypeInt,
+	},
+	"include_deleted": {
+		Type: schema.TypeBool,
+	},
+	"relationship_column_config": {
+		Type: schema.TypeString,
+	},
+	"child_relationship_config": {
+		Type: schema.TypeString,
+	},
+	"allow_writes": {
+		Type: schema.TypeBool,
+	},
+	"min_api_remaining_percent": {
+		Type: schema.TypeInt,
+	},
+	"prefetch_objects": {
+		Type: schema.TypeList,
+		Elem: &schema.Attribute{
+			Type: schema.TypeString,
+		},
+	},
+	"cache_max_records_per_object": {
+		Type: schema.TypeInt,
+	},
+	"cache_memory_budget_mb": {
+		Type: schema.TypeInt,
+	},
 }
 
 func ConfigInstance() interface{} {
@@ -85,9 +146,5 @@ func GetConfig(connection *plugin.Connection) salesforceConfig {
 	if config.ShowResultSizeError == nil {
 		config.ShowResultSizeError = &defaultShowResultSize
 	}
-	defaultCaseSenistiveSearch := true
-	if config.ShowResultSizeError == nil {
-		config.CaseSenistiveSearch = &defaultCaseSenistiveSearch
-	}
 	return config
 }
@@ -0,0 +1,244 @@
+package salesforce
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// globalSObject is the subset of an sObject entry returned by describeGlobal used by the plugin
+type globalSObject struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Custom     bool   `json:"custom"`
+	Queryable  bool   `json:"queryable"`
+	Deprecated bool   `json:"deprecatedAndHidden"`
+}
+
+// describeGlobal:: lists all sObjects available in the organization
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_describeGlobal.htm
+func describeGlobal(ctx context.Context, td *plugin.TableMapData, apiVersion string) ([]globalSObject, error) {
+	_, data, _, err := connectionRequest(ctx, td.ConnectionCache, td.Connection, http.MethodGet, fmt.Sprintf("services/data/v%s/sobjects", apiVersion), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		SObjects []globalSObject `json:"sobjects"`
+	}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	plugin.Logger(ctx).Debug("salesforce.describeGlobal", "sobject_count", len(result.SObjects))
+	return result.SObjects, nil
+}
+
+// getConfiguredObjectNames:: returns the Salesforce object names to generate dynamic tables for.
+// Exact names in the objects config are always used as is. If the config also has glob patterns,
+// e.g. "*__c", all queryable objects are listed through describeGlobal and the ones matching an
+// include pattern and no exclude pattern ("!*History") are added.
+func getConfiguredObjectNames(ctx context.Context, td *plugin.TableMapData, config salesforceConfig) []string {
+	if config.Objects == nil {
+		return []string{}
+	}
+
+	names := []string{}
+	includes := []string{}
+	excludes := []string{}
+	for _, object := range *config.Objects {
+		switch {
+		case strings.HasPrefix(object, "!"):
+			excludes = append(excludes, strings.TrimPrefix(object, "!"))
+		case strings.ContainsAny(object, "*?["):
+			includes = append(includes, object)
+		default:
+			names = append(names, object)
+		}
+	}
+
+	// Only exclusions configured, e.g. ["!*History"], means every queryable object except those
+	if len(includes) == 0 && len(excludes) > 0 && len(names) == 0 {
+		includes = []string{"*"}
+	}
+	if len(includes) == 0 {
+		return names
+	}
+
+	sObjects, err := describeGlobal(ctx, td, getAPIVersion(config))
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.getConfiguredObjectNames", "describe_global_error", err)
+		return names
+	}
+
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	for _, sObject := range sObjects {
+		if !sObject.Queryable || sObject.Deprecated || known[sObject.Name] {
+			continue
+		}
+		if matchesObjectPattern(includes, sObject.Name) && !matchesObjectPattern(excludes, sObject.Name) {
+			names = append(names, sObject.Name)
+			known[sObject.Name] = true
+		}
+	}
+	plugin.Logger(ctx).Debug("salesforce.getConfiguredObjectNames", "object_count", len(names))
+	return names
+}
+
+// matchesObjectPattern:: checks if the object name matches any of the glob patterns
+func matchesObjectPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// describeCacheEntry is the on-disk representation of a cached describe payload
+type describeCacheEntry struct {
+	InstanceURL  string                  `json:"instance_url"`
+	APIVersion   string                  `json:"api_version"`
+	Object       string                  `json:"object"`
+	LastModified string                  `json:"last_modified"`
+	FetchedAt    time.Time               `json:"fetched_at"`
+	Describe     simpleforce.SObjectMeta `json:"describe"`
+}
+
+// describeSObject:: returns the describe metadata of an object, or nil if the object can't be described.
+// Payloads are cached on disk per instance URL, API version and object. Within describe_cache_ttl the cached
+// payload is used without any API call; after that it is revalidated with If-Modified-Since, which only
+// downloads the payload again if the object changed.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_describe.htm
+func describeSObject(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, objectName string) *simpleforce.SObjectMeta {
+	client, err := connectRaw(ctx, td.ConnectionCache, td.Connection)
+	if err != nil || client == nil {
+		plugin.Logger(ctx).Error("salesforce.describeSObject", "object_name", objectName, "connection_error", err)
+		return nil
+	}
+
+	ttl := getDescribeCacheTTL(config)
+	apiVersion := getAPIVersion(config)
+	cacheFile := describeCacheFilePath(client.GetLoc(), apiVersion, objectName)
+	var entry *describeCacheEntry
+	if ttl > 0 {
+		entry = readDescribeCacheEntry(ctx, cacheFile)
+		if entry != nil && time.Since(entry.FetchedAt) < ttl {
+			return &entry.Describe
+		}
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	if entry != nil && entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+	statusCode, body, respHeader, err := connectionRequest(ctx, td.ConnectionCache, td.Connection, http.MethodGet, fmt.Sprintf("services/data/v%s/sobjects/%s/describe", apiVersion, objectName), nil, header)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.describeSObject", "object_name", objectName, "error", err)
+		return nil
+	}
+
+	if statusCode == http.StatusNotModified && entry != nil {
+		plugin.Logger(ctx).Debug("salesforce.describeSObject", "object_name", objectName, "cache", "not modified")
+		entry.FetchedAt = time.Now()
+	} else {
+		meta := simpleforce.SObjectMeta{}
+		if err = json.Unmarshal(body, &meta); err != nil {
+			plugin.Logger(ctx).Error("salesforce.describeSObject", "object_name", objectName, "decode_error", err)
+			return nil
+		}
+		entry = &describeCacheEntry{
+			InstanceURL:  client.GetLoc(),
+			APIVersion:   apiVersion,
+			Object:       objectName,
+			LastModified: respHeader.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Describe:     meta,
+		}
+	}
+
+	if ttl > 0 {
+		writeDescribeCacheEntry(ctx, cacheFile, entry)
+	}
+	return &entry.Describe
+}
+
+// Default describe_cache_ttl. Payloads are revalidated after it, so new fields show up within minutes
+// while unchanged objects cost a 304 response instead of the full payload.
+const defaultDescribeCacheTTL = 5 * time.Minute
+
+// getDescribeCacheTTL:: returns the configured describe cache TTL, 5 minutes by default
+func getDescribeCacheTTL(config salesforceConfig) time.Duration {
+	if config.DescribeCacheTTL != nil {
+		return time.Duration(*config.DescribeCacheTTL) * time.Second
+	}
+	return defaultDescribeCacheTTL
+}
+
+// describeCacheFilePath:: returns the cache file for the object under the Steampipe install directory
+func describeCacheFilePath(instanceURL string, apiVersion string, objectName string) string {
+	installDir := os.Getenv("STEAMPIPE_INSTALL_DIR")
+	if installDir == "" {
+		homeDir, _ := os.UserHomeDir()
+		installDir = filepath.Join(homeDir, ".steampipe")
+	}
+	key := sha256.Sum256([]byte(strings.Join([]string{instanceURL, apiVersion, objectName}, "|")))
+	return filepath.Join(installDir, "internal", "salesforce", "describe", hex.EncodeToString(key[:])+".json")
+}
+
+func readDescribeCacheEntry(ctx context.Context, cacheFile string) *describeCacheEntry {
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return nil
+	}
+	entry := &describeCacheEntry{}
+	if err = json.Unmarshal(data, entry); err != nil {
+		plugin.Logger(ctx).Warn("salesforce.readDescribeCacheEntry", "cache_file", cacheFile, "decode_error", err)
+		return nil
+	}
+	return entry
+}
+
+// writeDescribeCacheEntry:: writes the entry through a temporary file so concurrent plugin
+// processes never read a partially written cache file
+func writeDescribeCacheEntry(ctx context.Context, cacheFile string, entry *describeCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.writeDescribeCacheEntry", "encode_error", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(cacheFile), 0700); err != nil {
+		plugin.Logger(ctx).Warn("salesforce.writeDescribeCacheEntry", "mkdir_error", err)
+		return
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*.tmp")
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.writeDescribeCacheEntry", "write_error", err)
+		return
+	}
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), cacheFile)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		plugin.Logger(ctx).Warn("salesforce.writeDescribeCacheEntry", "write_error", err)
+	}
+}
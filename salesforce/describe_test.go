@@ -0,0 +1,54 @@
+package salesforce
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDescribeSObjectRevalidatesCache(t *testing.T) {
+	t.Setenv("STEAMPIPE_INSTALL_DIR", t.TempDir())
+	const lastModified = "Wed, 14 Oct 2026 08:00:00 GMT"
+
+	var mu sync.Mutex
+	var requests, notModified int
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(`{"name":"Account","fields":[{"name":"Id","type":"id"}]}`))
+	})
+	td := f.tableMapData("test", salesforceConfig{APIVersion: stringPtr("58.0")})
+	config := GetConfig(td.Connection)
+	ctx := testContext()
+
+	for i := 0; i < 2; i++ {
+		if meta := describeSObject(ctx, td, config, "Account"); meta == nil || (*meta)["name"] != "Account" {
+			t.Fatalf("describeSObject = %v, want the Account describe", meta)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("requests within the ttl = %d, want 1", requests)
+	}
+
+	// Once the ttl is over the cached payload is revalidated instead of downloaded again
+	client, _ := connectRaw(ctx, td.ConnectionCache, td.Connection)
+	cacheFile := describeCacheFilePath(client.GetLoc(), "58.0", "Account")
+	entry := readDescribeCacheEntry(ctx, cacheFile)
+	entry.FetchedAt = time.Now().Add(-defaultDescribeCacheTTL)
+	writeDescribeCacheEntry(ctx, cacheFile, entry)
+
+	if meta := describeSObject(ctx, td, config, "Account"); meta == nil || (*meta)["name"] != "Account" {
+		t.Fatalf("describeSObject after the ttl = %v, want the cached Account describe", meta)
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("requests = %d with %d not modified, want 2 with 1 not modified", requests, notModified)
+	}
+}
@@ -0,0 +1,130 @@
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Backoff of retried requests: the delay before attempt n is a random duration up to
+// retryBaseDelay * 2^n, capped at retryMaxDelay
+const (
+	retryMaxAttempts = 5
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 20 * time.Second
+)
+
+// Error codes of transient failures, Salesforce rejected the request without processing it
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/errorcodes.htm
+var retryableErrorCodes = []string{"SERVER_UNAVAILABLE"}
+
+// REQUEST_LIMIT_EXCEEDED is also returned once the daily API request limit is used up, e.g. "TotalRequests Limit
+// exceeded.", which retrying doesn't fix. Only the concurrent and per second limits are retried, e.g.
+// "ConcurrentPerOrgLongTxn Limit exceeded.".
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.salesforce_app_limits_cheatsheet.meta/salesforce_app_limits_cheatsheet/salesforce_app_limits_platform_api.htm
+var transientRequestLimitMessages = []string{"concurrent", "per second"}
+
+// Error codes returned for record ids that don't exist or aren't valid for the object
+var notFoundErrorCodes = []string{"NOT_FOUND", "MALFORMED_ID"}
+
+// hasErrorCode:: checks if the error is a Salesforce error with one of the codes, which
+// simpleforce formats as "... Error Code: NOT_FOUND"
+func hasErrorCode(err error, codes []string) bool {
+	if err == nil {
+		return false
+	}
+	for _, code := range codes {
+		if strings.Contains(err.Error(), "Error Code: "+code) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStatusCode:: checks if the error is a Salesforce error with the HTTP status code
+func hasStatusCode(err error, statusCode int) bool {
+	return err != nil && strings.Contains(err.Error(), fmt.Sprintf("http code: %d ", statusCode))
+}
+
+// isRetryableError:: checks if the request failed because of a transient error and can be sent again
+func isRetryableError(err error) bool {
+	return hasErrorCode(err, retryableErrorCodes) || hasStatusCode(err, 503) || isTransientRequestLimitError(err)
+}
+
+// isTransientRequestLimitError:: checks if the request exceeded a limit on concurrent or per second requests,
+// rather than the daily limit
+func isTransientRequestLimitError(err error) bool {
+	if !hasErrorCode(err, []string{"REQUEST_LIMIT_EXCEEDED"}) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, limit := range transientRequestLimitMessages {
+		if strings.Contains(message, limit) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFoundError:: checks if the requested record doesn't exist
+func isNotFoundError(err error) bool {
+	return hasErrorCode(err, notFoundErrorCodes)
+}
+
+// shouldIgnoreNotFoundError:: ignores not found errors of get calls, so a lookup of a missing id returns no rows
+func shouldIgnoreNotFoundError(_ context.Context, _ *plugin.QueryData, _ *plugin.HydrateData, err error) bool {
+	return isNotFoundError(err)
+}
+
+// parseResponseError:: returns the error of a failed response. Unlike simpleforce's ParseSalesforceError, the
+// status code is kept if the body isn't a Salesforce error, e.g. the HTML page of a 503 during maintenance.
+func parseResponseError(statusCode int, body []byte) error {
+	if strings.TrimSpace(string(body)) == "[]" {
+		// ParseSalesforceError expects at least one error in a JSON array
+		return fmt.Errorf("[simpleforce] Error. http code: %d Error Message: %w", statusCode, simpleforce.ErrFailure)
+	}
+	err := simpleforce.ParseSalesforceError(statusCode, body)
+	if errors.Is(err, simpleforce.ErrFailure) {
+		return fmt.Errorf("[simpleforce] Error. http code: %d Error Message: %w", statusCode, err)
+	}
+	return err
+}
+
+// retryDelay:: returns the backoff before the attempt, with full jitter so concurrent hydrates
+// hitting the same limit don't retry in lockstep
+func retryDelay(attempt int) time.Duration {
+	backoff := retryMaxDelay
+	if attempt < 16 && retryBaseDelay<<attempt < retryMaxDelay {
+		backoff = retryBaseDelay << attempt
+	}
+	return time.Duration(rand.Int63n(int64(backoff))) + 1
+}
+
+// withRetry:: calls fn until it succeeds, fails with an error that isn't retryable or runs out of attempts.
+// Retrying a single request keeps the pages a list already fetched, unlike retrying the whole hydrate.
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < retryMaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(attempt)
+			plugin.Logger(ctx).Warn("salesforce.withRetry", "attempt", attempt, "delay", delay.String(), "retryable_error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		err = fn()
+		if !isRetryableError(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", retryMaxAttempts, err)
+}
@@ -0,0 +1,93 @@
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// getSalesforceFieldType:: returns the describe type of a field, e.g. currency or multipicklist.
+// Falls back to the soapType if the describe payload has no type, e.g. ID for xsd:ID.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/field_types.htm
+func getSalesforceFieldType(field map[string]interface{}) string {
+	if fieldType, ok := field["type"].(string); ok && fieldType != "" {
+		return fieldType
+	}
+	soapType := strings.Split(field["soapType"].(string), ":")
+	return soapType[len(soapType)-1]
+}
+
+// isIDFieldType:: checks if the field holds record ids, which SOQL doesn't support LIKE on
+func isIDFieldType(fieldType string) bool {
+	switch fieldType {
+	case "id", "reference", "ID":
+		return true
+	}
+	return false
+}
+
+// setColumnTypeFromField:: sets the column type from the describe type of the field and returns the key column
+// to register for it, or nil if filters on the field can't be pushed down to Salesforce
+func setColumnTypeFromField(column *plugin.Column, field map[string]interface{}) *plugin.KeyColumn {
+	var operators []string
+	switch getSalesforceFieldType(field) {
+	case "string", "textarea", "picklist", "combobox", "phone", "email", "url", "encryptedstring":
+		column.Type = proto.ColumnType_STRING
+		operators = []string{"=", "<>", "~~", "!~~", "~~*", "!~~*"}
+	case "id", "reference", "ID":
+		column.Type = proto.ColumnType_STRING
+		operators = []string{"=", "<>"}
+	case "date", "datetime", "dateTime":
+		column.Type = proto.ColumnType_TIMESTAMP
+		operators = []string{"=", ">", ">=", "<=", "<"}
+	case "boolean":
+		column.Type = proto.ColumnType_BOOL
+		operators = []string{"=", "<>"}
+	case "double", "currency", "percent":
+		column.Type = proto.ColumnType_DOUBLE
+		operators = []string{"=", "<>", ">", ">=", "<=", "<"}
+	case "int", "long":
+		column.Type = proto.ColumnType_INT
+		operators = []string{"=", "<>", ">", ">=", "<=", "<"}
+	// SOQL compares time values as times while Postgres would compare the strings, so filters are not pushed down
+	case "time", "base64":
+		column.Type = proto.ColumnType_STRING
+	// Multi-select picklist values are returned as an array, filters like ? 'value' use INCLUDES
+	case "multipicklist":
+		column.Type = proto.ColumnType_JSON
+		column.Transform = column.Transform.Transform(multiPicklistToArray)
+		operators = []string{"?", "?|", "?&", "@>"}
+	// address, location (latitude and longitude), anyType and complexvalue
+	default:
+		column.Type = proto.ColumnType_JSON
+	}
+
+	// Long text area and encrypted fields, among others, can't be used in SOQL conditions
+	if filterable, ok := field["filterable"].(bool); (ok && !filterable) || len(operators) == 0 {
+		return nil
+	}
+	return &plugin.KeyColumn{Name: column.Name, Require: plugin.Optional, Operators: operators}
+}
+
+//// TRANSFORM FUNCTION
+
+// multiPicklistToArray:: converts a multi-select picklist value, e.g. "Red;Blue", into an array of values
+func multiPicklistToArray(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	switch value := d.Value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return value, nil
+	case string:
+		if value == "" {
+			return []string{}, nil
+		}
+		return strings.Split(value, ";"), nil
+	default:
+		return []string{fmt.Sprint(value)}, nil
+	}
+}
@@ -0,0 +1,96 @@
+package salesforce
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+)
+
+// Foreign key graphs of the record caches keyed by connection name. The graph of a connection
+// is rebuilt with its tables, e.g. when the connection config changes.
+var foreignKeyGraphs = map[string][]cache.KeyStruct{}
+var foreignKeyGraphsLock sync.RWMutex
+
+// getReferenceForeignKey:: returns the foreign key of a reference field that points to a single object, e.g.
+// AccountId of Contact. Polymorphic references like WhoId of Task are skipped, as their ids would be
+// looked up in every referenced object.
+func getReferenceForeignKey(field map[string]interface{}) (cache.ForeignKeyStruct, bool) {
+	fieldName, _ := field["name"].(string)
+	referenceTo, _ := field["referenceTo"].([]interface{})
+	if getSalesforceFieldType(field) != "reference" || fieldName == "" || len(referenceTo) != 1 {
+		return cache.ForeignKeyStruct{}, false
+	}
+	foreignTableName, ok := referenceTo[0].(string)
+	if !ok || foreignTableName == "" {
+		return cache.ForeignKeyStruct{}, false
+	}
+	return cache.ForeignKeyStruct{Key: fieldName, ForeignTableName: foreignTableName}, true
+}
+
+// buildForeignKeyGraph:: returns the key structs of the record cache for the objects of a connection and their
+// reference fields. Only references to objects that are tables of the connection, and listed in
+// prefetch_objects if that is set, are prefetched.
+func buildForeignKeyGraph(config salesforceConfig, foreignKeys map[string][]cache.ForeignKeyStruct) []cache.KeyStruct {
+	prefetchObjects := map[string]bool{}
+	if config.PrefetchObjects != nil {
+		for _, objectName := range *config.PrefetchObjects {
+			prefetchObjects[strings.ToLower(objectName)] = true
+		}
+	}
+
+	objectNames := make([]string, 0, len(foreignKeys))
+	for objectName := range foreignKeys {
+		objectNames = append(objectNames, objectName)
+	}
+	sort.Strings(objectNames)
+
+	graph := make([]cache.KeyStruct, 0, len(objectNames))
+	for _, objectName := range objectNames {
+		fks := []cache.ForeignKeyStruct{}
+		for _, fk := range foreignKeys[objectName] {
+			if _, ok := foreignKeys[fk.ForeignTableName]; !ok {
+				continue
+			}
+			if len(prefetchObjects) > 0 && !prefetchObjects[strings.ToLower(fk.ForeignTableName)] {
+				continue
+			}
+			fks = append(fks, fk)
+		}
+		graph = append(graph, cache.KeyStruct{
+			Name:              objectName,
+			Pk:                "Id",
+			Fk:                fks,
+			BulkDataPullByIds: bulkDataPullByIds(objectName),
+		})
+	}
+	return graph
+}
+
+// setForeignKeyGraph:: stores the foreign key graph built with the tables of the connection
+func setForeignKeyGraph(connectionName string, graph []cache.KeyStruct) {
+	foreignKeyGraphsLock.Lock()
+	defer foreignKeyGraphsLock.Unlock()
+	foreignKeyGraphs[connectionName] = graph
+}
+
+// getForeignKeyGraph:: returns the foreign key graph of the connection and a hash identifying it, so the record
+// cache of the connection is rebuilt when the relationships it prefetches change
+func getForeignKeyGraph(connectionName string) ([]cache.KeyStruct, string) {
+	foreignKeyGraphsLock.RLock()
+	defer foreignKeyGraphsLock.RUnlock()
+	graph := foreignKeyGraphs[connectionName]
+
+	hash := fnv.New64a()
+	for _, keyStruct := range graph {
+		fmt.Fprintf(hash, "%s:", keyStruct.Name)
+		for _, fk := range keyStruct.Fk {
+			fmt.Fprintf(hash, "%s>%s,", fk.Key, fk.ForeignTableName)
+		}
+		fmt.Fprint(hash, ";")
+	}
+	return graph, fmt.Sprintf("%x", hash.Sum64())
+}
@@ -0,0 +1,57 @@
+package salesforce
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+)
+
+func TestPluginTableDefinitionsForeignKeyGraph(t *testing.T) {
+	t.Setenv("STEAMPIPE_INSTALL_DIR", t.TempDir())
+	const idField = `{"name":"Id","type":"id","soapType":"tns:ID","filterable":true,"groupable":true}`
+	referenceField := func(name string, referenceTo string) string {
+		return fmt.Sprintf(`{"name":%q,"type":"reference","soapType":"tns:ID","filterable":true,"groupable":true,"referenceTo":[%q],"relationshipName":%q}`, name, referenceTo, strings.TrimSuffix(name, "Id"))
+	}
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		objectName := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/services/data/v58.0/sobjects/"), "/describe")
+		fields := []string{idField}
+		switch objectName {
+		case "Case":
+			fields = append(fields, referenceField("AccountId", "Account"), referenceField("ContactId", "Contact"))
+		case "Contact":
+			fields = append(fields, referenceField("AccountId", "Account"))
+		}
+		_, _ = fmt.Fprintf(w, `{"name":%q,"fields":[%s]}`, objectName, strings.Join(fields, ","))
+	})
+	td := f.tableMapData("foreign_key_graph", salesforceConfig{APIVersion: stringPtr("58.0")})
+
+	tables, err := pluginTableDefinitions(testContext(), td)
+	if err != nil {
+		t.Fatalf("pluginTableDefinitions: %v", err)
+	}
+	if tables["salesforce_case_aggregate"] == nil {
+		t.Error("salesforce_case has no aggregate table")
+	}
+
+	graph, _ := getForeignKeyGraph(td.Connection.Name)
+	var caseKeyStruct *cache.KeyStruct
+	for i := range graph {
+		if graph[i].Name == "Case" {
+			caseKeyStruct = &graph[i]
+		}
+	}
+	if caseKeyStruct == nil {
+		t.Fatal("foreign key graph has no Case")
+	}
+	want := []cache.ForeignKeyStruct{
+		{Key: "AccountId", ForeignTableName: "Account"},
+		{Key: "ContactId", ForeignTableName: "Contact"},
+	}
+	if !reflect.DeepEqual(caseKeyStruct.Fk, want) {
+		t.Errorf("Case foreign keys = %v, want %v", caseKeyStruct.Fk, want)
+	}
+}
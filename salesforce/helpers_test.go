@@ -0,0 +1,162 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/v3/cache"
+	"github.com/eko/gocache/v3/store"
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/connection"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+// testContext returns a context with the logger plugin.Logger expects
+func testContext() context.Context {
+	return context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// memoryStore is a synchronous stand-in for the connection cache store of the plugin
+type memoryStore struct {
+	mu    sync.Mutex
+	items map[any]any
+}
+
+func (s *memoryStore) Get(_ context.Context, key any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok := s.items[key]; ok {
+		return value, nil
+	}
+	return nil, store.NotFoundWithCause(fmt.Errorf("%v not found", key))
+}
+
+func (s *memoryStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	value, err := s.Get(ctx, key)
+	return value, 0, err
+}
+
+func (s *memoryStore) Set(_ context.Context, key any, value any, _ ...store.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = value
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+func (s *memoryStore) Invalidate(context.Context, ...store.InvalidateOption) error {
+	return nil
+}
+
+func (s *memoryStore) Clear(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = map[any]any{}
+	return nil
+}
+
+func (s *memoryStore) GetType() string {
+	return "memory"
+}
+
+// fakeSalesforce is a stand-in for the token endpoint and REST API of an org. Every login issues a new
+// access token, and REST API requests without a valid token fail with INVALID_SESSION_ID.
+type fakeSalesforce struct {
+	*httptest.Server
+	mu          sync.Mutex
+	logins      int
+	validTokens map[string]bool
+}
+
+// newFakeSalesforce returns an org serving the REST API requests with a valid token through handler
+func newFakeSalesforce(t *testing.T, handler http.HandlerFunc) *fakeSalesforce {
+	f := &fakeSalesforce{validTokens: map[string]bool{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/oauth2/token" {
+			f.mu.Lock()
+			f.logins++
+			token := fmt.Sprintf("token-%d", f.logins)
+			f.validTokens[token] = true
+			f.mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": token, "instance_url": f.URL})
+			return
+		}
+
+		f.mu.Lock()
+		valid := f.validTokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		f.mu.Unlock()
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// expireSessions revokes the access tokens issued so far
+func (f *fakeSalesforce) expireSessions() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.validTokens = map[string]bool{}
+}
+
+func (f *fakeSalesforce) loginCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins
+}
+
+// connection returns a connection logging in to the org with client credentials, with its own connection cache
+func (f *fakeSalesforce) connection(name string, config salesforceConfig) (*plugin.Connection, *connection.ConnectionCache) {
+	config.URL = &f.URL
+	config.ClientId = stringPtr("client-id")
+	config.ClientSecret = stringPtr("client-secret")
+	cc := connection.NewConnectionCache(name, cache.New[any](&memoryStore{items: map[any]any{}}))
+	return &plugin.Connection{Name: name, Config: config}, cc
+}
+
+// queryData returns the query data of a hydrate call of the connection
+func (f *fakeSalesforce) queryData(name string, config salesforceConfig) *plugin.QueryData {
+	c, cc := f.connection(name, config)
+	return &plugin.QueryData{
+		Connection:      c,
+		ConnectionCache: cc,
+		Table:           &plugin.Table{Name: "salesforce_test"},
+		QueryContext:    &plugin.QueryContext{},
+	}
+}
+
+// tableMapData returns the table map data of the connection, used while its tables are built
+func (f *fakeSalesforce) tableMapData(name string, config salesforceConfig) *plugin.TableMapData {
+	c, cc := f.connection(name, config)
+	return &plugin.TableMapData{Connection: c, ConnectionCache: cc}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
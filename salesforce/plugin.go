@@ -9,8 +9,7 @@ import (
 	"sync"
 
 	"github.com/iancoleman/strcase"
-	"github.com/simpleforce/simpleforce"
-	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
@@ -19,6 +18,29 @@ const pluginName = "steampipe-plugin-salesforce"
 
 type contextKey string
 
+// Maximum number of objects described in parallel while building dynamic tables
+const maxConcurrentDescribes = 10
+
+// Table names of the static tables, keyed by object name
+var staticTableNames = map[string]string{
+	"Account":                 "salesforce_account",
+	"AccountContactRole":      "salesforce_account_contact_role",
+	"Asset":                   "salesforce_asset",
+	"Case":                    "salesforce_case",
+	"Contact":                 "salesforce_contact",
+	"Contract":                "salesforce_contract",
+	"Lead":                    "salesforce_lead",
+	"ObjectPermissions":       "salesforce_object_permission",
+	"Opportunity":             "salesforce_opportunity",
+	"OpportunityContactRole":  "salesforce_opportunity_contact_role",
+	"Order":                   "salesforce_order",
+	"PermissionSet":           "salesforce_permission_set",
+	"PermissionSetAssignment": "salesforce_permission_set_assignment",
+	"Pricebook2":              "salesforce_pricebook",
+	"Product2":                "salesforce_product",
+	"User":                    "salesforce_user",
+}
+
 func Plugin(ctx context.Context) *plugin.Plugin {
 	p := &plugin.Plugin{
 		Name:             pluginName,
@@ -27,8 +49,14 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			NewInstance: ConfigInstance,
 			Schema:      ConfigSchema,
 		},
+		DefaultGetConfig: &plugin.GetConfig{
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: shouldIgnoreNotFoundError,
+			},
+		},
 		SchemaMode:   plugin.SchemaModeDynamic,
 		TableMapFunc: pluginTableDefinitions,
+		RateLimiters: salesforceRateLimiters,
 	}
 
 	return p
@@ -38,6 +66,10 @@ type dynamicMap struct {
 	cols              []*plugin.Column
 	keyColumns        plugin.KeyColumnSlice
 	salesforceColumns map[string]string
+	// reference fields of the columns, which the record cache prefetches
+	foreignKeys []cache.ForeignKeyStruct
+	// columns of the fields that can be grouped by, which the aggregate table of the object has
+	groupableColumns map[string]bool
 }
 
 func pluginTableDefinitions(ctx context.Context, td *plugin.TableMapData) (map[string]*plugin.Table, error) {
@@ -49,8 +81,6 @@ func pluginTableDefinitions(ctx context.Context, td *plugin.TableMapData) (map[s
 		plugin.Logger(ctx).Warn("salesforce.pluginTableDefinitions", "connection_error: unable to generate dynamic tables because of invalid steampipe salesforce configuration", err)
 	}
 
-	staticTables := []string{"Account", "AccountContactRole", "Asset", "Contact", "Contract", "Lead", "Opportunity", "OpportunityContactRole", "Order", "Pricebook2", "Product2", "User", "PermissionSet", "PermissionSetAssignment", "ObjectPermissions"}
-
 	dynamicColumnsMap := map[string]dynamicMap{}
 	var mapLock sync.Mutex
 	config := GetConfig(td.Connection)
@@ -76,18 +106,24 @@ func pluginTableDefinitions(ctx context.Context, td *plugin.TableMapData) (map[s
 		}
 	}
 
+	relationshipColumnConfig := getRelationshipColumnConfig(ctx, config)
+	childRelationshipConfig := getChildRelationshipConfig(ctx, config)
+
 	// If Salesforce client was obtained, don't generate dynamic columns for
 	// defined static tables
 	if client != nil {
 		var wgd sync.WaitGroup
-		wgd.Add(len(staticTables))
-		for _, st := range staticTables {
+		wgd.Add(len(staticTableNames))
+		for st := range staticTableNames {
 			go func(staticTable string) {
 				defer wgd.Done()
-				dynamicCols, dynamicKeyColumns, salesforceCols := dynamicColumns(ctx, client, staticTable, config, getUserDefinedDynamicColumns(userDefinedDynamicColumns, staticTable))
+				schema := describeTableSchema(ctx, td, config, staticTable, getUserDefinedDynamicColumns(userDefinedDynamicColumns, staticTable), relationshipColumnConfig[staticTable], childRelationshipConfig[staticTable])
+				if schema == nil {
+					return
+				}
 				mapLock.Lock()
-				dynamicColumnsMap[staticTable] = dynamicMap{dynamicCols, dynamicKeyColumns, salesforceCols}
-				defer mapLock.Unlock()
+				dynamicColumnsMap[staticTable] = *schema
+				mapLock.Unlock()
 			}(st)
 		}
 		wgd.Wait()
@@ -118,6 +154,10 @@ func pluginTableDefinitions(ctx context.Context, td *plugin.TableMapData) (map[s
 			"User":                    SalesforceUser(ctx, dynamicColumnsMap["User"], config),
 			"Case":                    SalesforceCase(ctx, dynamicColumnsMap["Case"], config),
 			"Field":                   SalesforceField(ctx, dynamicColumnsMap, config),
+			"Query":                   SalesforceQuery(ctx, config),
+			"Search":                  SalesforceSearch(ctx, config),
+			"OrgLimit":                SalesforceOrgLimit(ctx, config),
+			"RecordCache":             SalesforceRecordCache(ctx, config),
 		}
 	} else {
 		tables = map[string]*plugin.Table{
@@ -138,169 +178,120 @@ func pluginTableDefinitions(ctx context.Context, td *plugin.TableMapData) (map[s
 			"salesforce_user":                      SalesforceUser(ctx, dynamicColumnsMap["User"], config),
 			"salesforce_case":                      SalesforceCase(ctx, dynamicColumnsMap["Case"], config),
 			"salesforce_field":                     SalesforceField(ctx, dynamicColumnsMap, config),
+			"salesforce_query":                     SalesforceQuery(ctx, config),
+			"salesforce_search":                    SalesforceSearch(ctx, config),
+			"salesforce_org_limit":                 SalesforceOrgLimit(ctx, config),
+			"salesforce_record_cache":              SalesforceRecordCache(ctx, config),
 		}
 	}
 
-	var re = regexp.MustCompile(`\d+`)
-	var substitution = ``
-	salesforceTables := []string{}
-	if config.Objects != nil && len(*config.Objects) > 0 {
-		for _, tableName := range *config.Objects {
-			var pluginTableName string
-			if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
-				pluginTableName = strcase.ToSnake(re.ReplaceAllString(tableName, substitution))
-			} else {
-				pluginTableName = "salesforce_" + strcase.ToSnake(re.ReplaceAllString(tableName, substitution))
-			}
-			if _, ok := tables[pluginTableName]; !ok {
-				salesforceTables = append(salesforceTables, tableName)
-			}
+	// Action tables change data, so they are only available if the connection allows writes
+	if config.AllowWrites != nil && *config.AllowWrites {
+		if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+			tables["ActionCreate"] = SalesforceActionCreate(ctx, config)
+			tables["ActionUpdate"] = SalesforceActionUpdate(ctx, config)
+			tables["ActionDelete"] = SalesforceActionDelete(ctx, config)
+		} else {
+			tables["salesforce_action_create"] = SalesforceActionCreate(ctx, config)
+			tables["salesforce_action_update"] = SalesforceActionUpdate(ctx, config)
+			tables["salesforce_action_delete"] = SalesforceActionDelete(ctx, config)
 		}
 	}
 
 	if client == nil {
 		plugin.Logger(ctx).Warn("salesforce.pluginTableDefinitions", "client_not_found: unable to generate dynamic tables because of invalid steampipe salesforce configuration", err)
+		setForeignKeyGraph(td.Connection.Name, nil)
+		tagHydrateCalls(tables)
 		return tables, nil
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(len(salesforceTables))
-	for _, sfTable := range salesforceTables {
-		var tableName string
+	// Object names of the static and dynamic tables that have a schema, keyed by table name
+	objectTableNames := map[string]string{}
+	objectSchemas := map[string]dynamicMap{}
+	// Reference fields of the static and dynamic tables, keyed by object name
+	foreignKeys := map[string][]cache.ForeignKeyStruct{}
+	for objectName, schema := range dynamicColumnsMap {
+		foreignKeys[objectName] = schema.foreignKeys
+		objectSchemas[objectName] = schema
 		if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
-			tableName = sfTable
+			objectTableNames[objectName] = objectName
 		} else {
-			tableName = "salesforce_" + strcase.ToSnake(re.ReplaceAllString(sfTable, substitution))
+			objectTableNames[staticTableNames[objectName]] = objectName
 		}
-		if tables[tableName] != nil {
-			wg.Done()
-			continue
+	}
+
+	var re = regexp.MustCompile(`\d+`)
+	var substitution = ``
+	salesforceTables := map[string]string{}
+	for _, objectName := range getConfiguredObjectNames(ctx, td, config) {
+		var pluginTableName string
+		if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+			pluginTableName = objectName
+		} else {
+			pluginTableName = "salesforce_" + strcase.ToSnake(re.ReplaceAllString(objectName, substitution))
+		}
+		if _, ok := tables[pluginTableName]; !ok {
+			salesforceTables[pluginTableName] = objectName
 		}
-		go func(name string) {
+	}
+
+	var wg sync.WaitGroup
+	// Limit the number of concurrent describe calls, as discovery can yield hundreds of objects
+	describeLimit := make(chan struct{}, maxConcurrentDescribes)
+	wg.Add(len(salesforceTables))
+	for pluginTableName, sfTable := range salesforceTables {
+		go func(tableName string, name string) {
 			defer wg.Done()
-			if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
-				tableName = name
-			} else {
-				tableName = "salesforce_" + strcase.ToSnake(re.ReplaceAllString(name, substitution))
-			}
+			describeLimit <- struct{}{}
+			defer func() { <-describeLimit }()
 			plugin.Logger(ctx).Debug("salesforce.pluginTableDefinitions", "object_name", name, "table_name", tableName)
-			ctx = context.WithValue(ctx, contextKey("PluginTableName"), tableName)
-			ctx = context.WithValue(ctx, contextKey("SalesforceTableName"), name)
-			table := generateDynamicTables(ctx, client, config, getUserDefinedDynamicColumns(userDefinedDynamicColumns, tableName))
+			tableCtx := context.WithValue(ctx, contextKey("PluginTableName"), tableName)
+			tableCtx = context.WithValue(tableCtx, contextKey("SalesforceTableName"), name)
+			table, schema := generateDynamicTables(tableCtx, td, config, getUserDefinedDynamicColumns(userDefinedDynamicColumns, name), relationshipColumnConfig[name], childRelationshipConfig[name])
 			// Ignore if the requested Salesforce object is not present.
 			if table != nil {
+				mapLock.Lock()
 				tables[tableName] = table
+				objectSchemas[name] = *schema
+				objectTableNames[tableName] = name
+				foreignKeys[name] = schema.foreignKeys
+				mapLock.Unlock()
 			}
-		}(sfTable)
+		}(pluginTableName, sfTable)
 	}
 	wg.Wait()
+
+	// Aggregate tables of the objects, e.g. salesforce_opportunity_aggregate for salesforce_opportunity
+	for tableName, objectName := range objectTableNames {
+		aggregateTableName := getAggregateTableName(config, tableName)
+		if _, ok := tables[aggregateTableName]; !ok {
+			tables[aggregateTableName] = SalesforceObjectAggregate(ctx, aggregateTableName, objectName, objectSchemas[objectName])
+		}
+	}
+	setForeignKeyGraph(td.Connection.Name, buildForeignKeyGraph(config, foreignKeys))
+	tagHydrateCalls(tables)
 	return tables, nil
 }
 
-func generateDynamicTables(ctx context.Context, client *simpleforce.Client, config salesforceConfig, userDefinedDynamicColumns map[string]bool) *plugin.Table {
+func generateDynamicTables(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, userDefinedDynamicColumns map[string]bool, relationshipPaths []string, childRelationships []string) (*plugin.Table, *dynamicMap) {
 	// Get the query for the metric (required)
 	salesforceTableName := ctx.Value(contextKey("SalesforceTableName")).(string)
 	tableName := ctx.Value(contextKey("PluginTableName")).(string)
 
-	sObjectMeta := client.SObject(salesforceTableName).Describe()
-	if sObjectMeta == nil {
-		plugin.Logger(ctx).Error("salesforce.generateDynamicTables", fmt.Sprintf("Object %s not found in salesforce", salesforceTableName))
-		return nil
-	}
-
-	// Top columns
-	cols := []*plugin.Column{}
-	salesforceCols := map[string]string{}
-	// Key columns
-	keyColumns := plugin.KeyColumnSlice{}
-
-	salesforceObjectMetadata := *sObjectMeta
-	salesforceObjectMetadataAsByte, err := json.Marshal(salesforceObjectMetadata["fields"])
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.generateDynamicTables", "json marshal error", err)
-	}
-
-	salesforceObjectFields := []map[string]interface{}{}
-	err = json.Unmarshal(salesforceObjectMetadataAsByte, &salesforceObjectFields)
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.generateDynamicTables", "json unmarshal error", err)
-	}
-
-	for _, properties := range salesforceObjectFields {
-		if properties["name"] == nil {
-			continue
-		}
-		fieldName := properties["name"].(string)
-		compoundFieldName := properties["compoundFieldName"]
-		if compoundFieldName != nil && compoundFieldName.(string) != fieldName {
-			continue
-		}
-
-		if properties["soapType"] == nil {
-			continue
-		}
-		soapType := strings.Split((properties["soapType"]).(string), ":")
-		fieldType := soapType[len(soapType)-1]
-
-		// Column dynamic generation
-		// Don't convert to snake case since field names can have underscores in
-		// them, so it's impossible to convert from snake case back to camel case
-		// to match the original field name. Also, if we convert to snake case,
-		// custom fields like "TestField" and "Test_Field" will result in duplicates
-		var columnFieldName string
-
-		// keep the field name as it is if NamingConvention is set to api_native
-		if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
-			columnFieldName = fieldName
-		} else if strings.HasSuffix(fieldName, "__c") {
-			columnFieldName = strings.ToLower(fieldName)
-			_, ok := userDefinedDynamicColumns[columnFieldName]
-			if len(userDefinedDynamicColumns) != 0 && !ok {
-				continue
-			}
-		} else {
-			columnFieldName = strcase.ToSnake(fieldName)
-		}
-
-		column := plugin.Column{
-			Name:        columnFieldName,
-			Description: fmt.Sprintf("%s.", properties["label"].(string)),
-			Transform:   transform.FromP(getFieldFromSObjectMap, fieldName),
-		}
-		// Adding column type in the map to help in qual handling
-		salesforceCols[columnFieldName] = fieldType
-
-		// Set column type based on the `soapType` from salesforce schema
-		switch fieldType {
-		case "string", "ID":
-			column.Type = proto.ColumnType_STRING
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", "<>"}})
-		case "date", "dateTime":
-			column.Type = proto.ColumnType_TIMESTAMP
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		case "boolean":
-			column.Type = proto.ColumnType_BOOL
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", "<>"}})
-		case "double":
-			column.Type = proto.ColumnType_DOUBLE
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		case "int":
-			column.Type = proto.ColumnType_INT
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		default:
-			column.Type = proto.ColumnType_JSON
-		}
-		cols = append(cols, &column)
+	schema := describeTableSchema(ctx, td, config, salesforceTableName, userDefinedDynamicColumns, relationshipPaths, childRelationships)
+	if schema == nil {
+		return nil, nil
 	}
+	cols, keyColumns, salesforceCols := schema.cols, schema.keyColumns, schema.salesforceColumns
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range cols {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	Table := plugin.Table{
 		Name:        tableName,
-		Description: fmt.Sprintf("Represents Salesforce object %s.", salesforceObjectMetadata["name"]),
+		Description: fmt.Sprintf("Represents Salesforce object %s.", salesforceTableName),
 		List: &plugin.ListConfig{
 			KeyColumns: keyColumns,
 			Hydrate:    listSalesforceObjectsByTable(salesforceTableName, salesforceCols, queryColumnsMap),
@@ -311,7 +302,7 @@ func generateDynamicTables(ctx context.Context, client *simpleforce.Client, conf
 		},
 		Columns: cols,
 	}
-	return &Table
+	return &Table, schema
 }
 
 // set GetConfig parameter based on NamingConvention value
@@ -0,0 +1,38 @@
+package salesforce
+
+import (
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
+)
+
+// Default limiter for the Salesforce API calls of each connection. It can be tuned or replaced with a limiter block
+// of the same name in the plugin config, and targeted at specific calls through the action tag.
+// Ref: https://steampipe.io/docs/guides/limiter
+var salesforceRateLimiters = []*rate_limiter.Definition{
+	{
+		Name:           "salesforce_api",
+		FillRate:       25,
+		BucketSize:     25,
+		MaxConcurrency: 10,
+		Scope:          []string{"connection"},
+		Where:          "service = 'salesforce'",
+	},
+}
+
+// salesforceTags:: returns the rate limiter tags of a hydrate call, e.g. action = 'list'
+func salesforceTags(action string) map[string]string {
+	return map[string]string{"service": "salesforce", "action": action}
+}
+
+// tagHydrateCalls:: tags the list and get calls of tables that don't set their own tags, so all calls
+// to Salesforce go through the rate limiters
+func tagHydrateCalls(tables map[string]*plugin.Table) {
+	for _, table := range tables {
+		if table.List != nil && table.List.Tags == nil {
+			table.List.Tags = salesforceTags("list")
+		}
+		if table.Get != nil && table.Get.Tags == nil {
+			table.Get.Tags = salesforceTags("get")
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Key of the connection's record cache scope in the connection cache
+const recordCacheScopeKey = "record_cache_scope"
+
+// Default limits of the record caches
+const (
+	defaultCacheMaxRecordsPerObject = 10000
+	defaultCacheMemoryBudgetMB      = 256
+)
+
+// connectionRecordCache is the record cache of a connection, with the org id, user, foreign key graph and
+// limits it was built for
+type connectionRecordCache struct {
+	key   string
+	cache *cache.CacheUtil
+}
+
+// Record caches keyed by connection name, so connections to different orgs, e.g. a sandbox and production in
+// an aggregator, never share records. A connection's cache is replaced when its key changes.
+var recordCaches = map[string]*connectionRecordCache{}
+var recordCachesLock sync.Mutex
+
+// Memory budget shared by the record caches of all connections, and the budget configured by each connection
+var recordCacheBudget = cache.NewBudget(defaultCacheMemoryBudgetMB << 20)
+var recordCacheBudgetsMB = map[string]int{}
+
+// getCacheMaxRecordsPerObject:: returns the cache_max_records_per_object of the connection, 0 means unbounded
+func getCacheMaxRecordsPerObject(config salesforceConfig) int {
+	if config.CacheMaxRecordsPerObject != nil {
+		return *config.CacheMaxRecordsPerObject
+	}
+	return defaultCacheMaxRecordsPerObject
+}
+
+// setRecordCacheBudget:: records the cache_memory_budget_mb of the connection. The shared budget is the largest
+// budget configured by a connection using the cache, and 0 in any connection makes it unbounded.
+// The caller holds recordCachesLock.
+func setRecordCacheBudget(connectionName string, config salesforceConfig) {
+	budgetMB := defaultCacheMemoryBudgetMB
+	if config.CacheMemoryBudgetMB != nil {
+		budgetMB = *config.CacheMemoryBudgetMB
+	}
+	recordCacheBudgetsMB[connectionName] = budgetMB
+
+	maxMB := 0
+	for _, connectionBudgetMB := range recordCacheBudgetsMB {
+		if connectionBudgetMB <= 0 {
+			maxMB = 0
+			break
+		}
+		if connectionBudgetMB > maxMB {
+			maxMB = connectionBudgetMB
+		}
+	}
+	if maxBytes := int64(maxMB) << 20; maxBytes != recordCacheBudget.MaxBytes() {
+		recordCacheBudget.SetMaxBytes(maxBytes)
+	}
+}
+
+// getRecordCacheUser:: returns the user the connection logs in as, which decides the records it can see
+func getRecordCacheUser(config salesforceConfig) string {
+	switch getAuthMode(config) {
+	case authModeClientCredentials:
+		// Client credentials run as the integration user of the connected app
+		return "client_id:" + *config.ClientId
+	default:
+		if config.Username != nil {
+			return strings.ToLower(*config.Username)
+		}
+	}
+	return ""
+}
+
+// getRecordCacheScope:: returns the org id and user of the connection, e.g. 00D5g000004ABCD/admin@example.com.
+// The org id is queried once per connection and kept in the connection cache.
+func getRecordCacheScope(ctx context.Context, d *plugin.QueryData) (string, error) {
+	if cachedData, ok := d.ConnectionCache.Get(ctx, recordCacheScopeKey); ok {
+		return cachedData.(string), nil
+	}
+
+	result, err := querySalesforce(ctx, d, "SELECT Id FROM Organization")
+	if err != nil {
+		return "", err
+	}
+	records := []map[string]interface{}{}
+	if err = decodeQueryResult(ctx, result.Records, &records); err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("unable to get the organization id of connection %s", d.Connection.Name)
+	}
+	orgID, _ := records[0]["Id"].(string)
+	scope := orgID + "/" + getRecordCacheUser(GetConfig(d.Connection))
+
+	if err = d.ConnectionCache.Set(ctx, recordCacheScopeKey, scope); err != nil {
+		plugin.Logger(ctx).Error("salesforce.getRecordCacheScope", "cache-set", err)
+	}
+	return scope, nil
+}
+
+// getRecordCache:: returns the record cache of the connection. If the org id can't be looked up, the cache
+// is scoped to the connection name instead. If the scope, foreign key graph or limits of the connection changed,
+// the cache is rebuilt and the records of the previous one are released from the memory budget.
+func getRecordCache(ctx context.Context, d *plugin.QueryData) *cache.CacheUtil {
+	scope, err := getRecordCacheScope(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.getRecordCache", "connection", d.Connection.Name, "org_id_error", err)
+		scope = "connection:" + d.Connection.Name
+	}
+	config := GetConfig(d.Connection)
+	maxRecordsPerObject := getCacheMaxRecordsPerObject(config)
+	graph, graphHash := getForeignKeyGraph(d.Connection.Name)
+	key := fmt.Sprintf("%s#%s#%d", scope, graphHash, maxRecordsPerObject)
+
+	recordCachesLock.Lock()
+	defer recordCachesLock.Unlock()
+	setRecordCacheBudget(d.Connection.Name, config)
+	recordCache, ok := recordCaches[d.Connection.Name]
+	if ok && recordCache.key == key {
+		return recordCache.cache
+	}
+	if ok {
+		plugin.Logger(ctx).Debug("salesforce.getRecordCache", "connection", d.Connection.Name, "rebuilding cache", key)
+		recordCache.cache.Clear()
+	}
+	recordCache = &connectionRecordCache{
+		key: key,
+		cache: cache.NewCacheUtil(graph, cacheExpiration, batchSize, idFormatter, cache.Limits{
+			MaxRecordsPerTable: maxRecordsPerObject,
+			Budget:             recordCacheBudget,
+		}),
+	}
+	recordCaches[d.Connection.Name] = recordCache
+	return recordCache.cache
+}
+
+// removeCachedRecord:: removes a record changed through the connection from its record cache, if the connection
+// has one, so later reads fetch the record again instead of returning the values cached before the change
+func removeCachedRecord(ctx context.Context, connectionName string, objectName string, id string) {
+	recordCachesLock.Lock()
+	recordCache, ok := recordCaches[connectionName]
+	recordCachesLock.Unlock()
+	if !ok {
+		return
+	}
+	plugin.Logger(ctx).Debug("salesforce.removeCachedRecord", "connection", connectionName, "object_name", objectName, "id", id)
+	recordCache.cache.RemoveRecord(objectName, id)
+}
@@ -0,0 +1,83 @@
+package salesforce
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// newFakeOrg returns an org with the organization id, serving the query of its org id
+func newFakeOrg(t *testing.T, orgID string) *fakeSalesforce {
+	return newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "SELECT Id FROM Organization" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`[{"message":"The requested resource does not exist","errorCode":"NOT_FOUND"}]`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Organization"},"Id":%q}]}`, orgID)
+	})
+}
+
+func TestRecordCacheIsPerConnection(t *testing.T) {
+	ctx := testContext()
+	graph := []cache.KeyStruct{{Name: "Account", Pk: "Id"}}
+	columns := map[string]*plugin.Column{"Id": {Name: "id"}, "Name": {Name: "name"}}
+
+	sandbox := newFakeOrg(t, "00D000000000001").queryData("record_cache_sandbox", salesforceConfig{})
+	production := newFakeOrg(t, "00D000000000002").queryData("record_cache_production", salesforceConfig{})
+	setForeignKeyGraph(sandbox.Connection.Name, graph)
+	setForeignKeyGraph(production.Connection.Name, graph)
+
+	// Both orgs have an account with the same id, listed on the sandbox only
+	sandboxCache := getRecordCache(ctx, sandbox)
+	sandboxCache.AddIdsToForeignTableCache(ctx, "Account", map[string]interface{}{"Id": "001000000000001", "Name": "Sandbox account"})
+
+	productionCache := getRecordCache(ctx, production)
+	if productionCache == sandboxCache {
+		t.Fatal("connections to different orgs share a record cache")
+	}
+	record, err := productionCache.GetRecordByIdAndBuildCache(ctx, production, nil, "Account", "001000000000001", columns)
+	if err != nil {
+		t.Fatalf("GetRecordByIdAndBuildCache: %v", err)
+	}
+	if record != nil {
+		t.Errorf("production record = %v, want the record of the sandbox not to be returned", record)
+	}
+
+	record, err = sandboxCache.GetRecordByIdAndBuildCache(ctx, sandbox, nil, "Account", "001000000000001", columns)
+	if err != nil {
+		t.Fatalf("GetRecordByIdAndBuildCache: %v", err)
+	}
+	if name := record.(map[string]interface{})["Name"]; name != "Sandbox account" {
+		t.Errorf("sandbox record name = %v, want Sandbox account", name)
+	}
+}
+
+func TestRecordCacheIsRebuiltWhenLimitsChange(t *testing.T) {
+	ctx := testContext()
+	d := newFakeOrg(t, "00D000000000003").queryData("record_cache_rebuilt", salesforceConfig{})
+	setForeignKeyGraph(d.Connection.Name, []cache.KeyStruct{{Name: "Account", Pk: "Id"}})
+
+	usedBytes := recordCacheBudget.UsedBytes()
+	recordCache := getRecordCache(ctx, d)
+	recordCache.AddIdsToForeignTableCache(ctx, "Account", map[string]interface{}{"Id": "001000000000001", "Name": "Acme"})
+	if recordCacheBudget.UsedBytes() <= usedBytes {
+		t.Fatal("cached record isn't counted against the memory budget")
+	}
+
+	// A connection config change replaces the cache and releases the memory of its records
+	d.Connection.Config = salesforceConfig{URL: GetConfig(d.Connection).URL, ClientId: stringPtr("client-id"), ClientSecret: stringPtr("client-secret"), CacheMaxRecordsPerObject: intPtr(10)}
+	rebuilt := getRecordCache(ctx, d)
+	if rebuilt == recordCache {
+		t.Fatal("record cache wasn't rebuilt after cache_max_records_per_object changed")
+	}
+	if got := recordCacheBudget.UsedBytes(); got != usedBytes {
+		t.Errorf("used bytes = %d, want %d after the cache was rebuilt", got, usedBytes)
+	}
+	if got := getRecordCache(ctx, d); got != rebuilt {
+		t.Error("record cache is rebuilt although the connection didn't change")
+	}
+}
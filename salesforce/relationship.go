@@ -0,0 +1,158 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// relationshipPath is the dotted path of a relationship column, e.g. Owner.Name, used as its transform param.
+// Column names can't be converted back to a relationship path, so the path is read from the column itself.
+type relationshipPath string
+
+// getRelationshipColumnPath:: returns the relationship path of a relationship column
+func getRelationshipColumnPath(column *plugin.Column) (string, bool) {
+	if column.Transform == nil || len(column.Transform.Transforms) == 0 {
+		return "", false
+	}
+	path, ok := column.Transform.Transforms[0].Param.(relationshipPath)
+	return string(path), ok
+}
+
+// getRelationshipColumnName:: returns the column name for a relationship path, e.g. Owner.Name => owner__name
+// and Custom_Account__r.Name => custom_account__r__name. With api_native naming the path is used as is.
+func getRelationshipColumnName(config salesforceConfig, path string) string {
+	if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+		return path
+	}
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		if strings.HasSuffix(part, "__r") || strings.HasSuffix(part, "__c") {
+			parts[i] = strings.ToLower(part)
+		} else {
+			parts[i] = strcase.ToSnake(part)
+		}
+	}
+	return strings.Join(parts, "__")
+}
+
+// getRelationshipColumnConfig:: returns the relationship paths configured per object in relationship_column_config
+func getRelationshipColumnConfig(ctx context.Context, config salesforceConfig) map[string][]string {
+	relationshipColumns := map[string][]string{}
+	if config.RelationshipColumnConfig == nil {
+		return relationshipColumns
+	}
+
+	relationshipColumnConfigs := []UserDefinedDynamicColumnConfig{}
+	err := json.Unmarshal([]byte(*config.RelationshipColumnConfig), &relationshipColumnConfigs)
+	if err != nil {
+		plugin.Logger(ctx).Warn("salesforce.getRelationshipColumnConfig", "invalid relationship_column_config JSON", err)
+		return relationshipColumns
+	}
+	for _, relationshipColumnConfig := range relationshipColumnConfigs {
+		relationshipColumns[relationshipColumnConfig.Name] = append(relationshipColumns[relationshipColumnConfig.Name], relationshipColumnConfig.Columns...)
+	}
+	return relationshipColumns
+}
+
+// relationshipColumns:: returns typed columns for the parent relationship paths of an object, e.g. Owner.Name or
+// Account.Owner.Email. Each relationship is resolved through the relationshipName and referenceTo properties of
+// the describe fields; for polymorphic relationships the first referenced object is used.
+func relationshipColumns(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, objectFields []map[string]interface{}, paths []string) ([]*plugin.Column, plugin.KeyColumnSlice, map[string]string) {
+	cols := []*plugin.Column{}
+	keyColumns := plugin.KeyColumnSlice{}
+	salesforceCols := map[string]string{}
+
+	for _, path := range paths {
+		field := resolveRelationshipField(ctx, td, config, objectFields, path)
+		if field == nil {
+			plugin.Logger(ctx).Warn("salesforce.relationshipColumns", "unable to resolve relationship column", path)
+			continue
+		}
+		fieldType := getSalesforceFieldType(field)
+
+		columnName := getRelationshipColumnName(config, path)
+		column := plugin.Column{
+			Name:        columnName,
+			Description: fmt.Sprintf("%s (%s).", field["label"], path),
+			Transform:   transform.FromP(getFieldFromSObjectMapByPath, relationshipPath(path)),
+		}
+		salesforceCols[columnName] = fieldType
+
+		// Set column type based on the `type` from salesforce schema
+		if keyColumn := setColumnTypeFromField(&column, field); keyColumn != nil {
+			keyColumns = append(keyColumns, keyColumn)
+		}
+		cols = append(cols, &column)
+	}
+	return cols, keyColumns, salesforceCols
+}
+
+// resolveRelationshipField:: walks the relationship path from the object fields and returns the describe field
+// of the last path element, or nil if any relationship or field doesn't exist
+func resolveRelationshipField(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, objectFields []map[string]interface{}, path string) map[string]interface{} {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+
+	fields := objectFields
+	for _, relationshipName := range parts[:len(parts)-1] {
+		var referenceTo string
+		for _, field := range fields {
+			if name, ok := field["relationshipName"].(string); ok && strings.EqualFold(name, relationshipName) {
+				if references, ok := field["referenceTo"].([]interface{}); ok && len(references) > 0 {
+					referenceTo, _ = references[0].(string)
+				}
+				break
+			}
+		}
+		if referenceTo == "" {
+			return nil
+		}
+
+		sObjectMeta := describeSObject(ctx, td, config, referenceTo)
+		if sObjectMeta == nil {
+			return nil
+		}
+		fields = getDescribeFields(ctx, *sObjectMeta)
+	}
+
+	fieldName := parts[len(parts)-1]
+	for _, field := range fields {
+		if name, ok := field["name"].(string); ok && strings.EqualFold(name, fieldName) && field["soapType"] != nil {
+			return field
+		}
+	}
+	return nil
+}
+
+//// TRANSFORM FUNCTION
+
+// getFieldFromSObjectMapByPath:: returns a parent relationship field from a record. REST records nest the
+// parent record under the relationship name, while Bulk API records use the dotted path as key.
+func getFieldFromSObjectMapByPath(ctx context.Context, d *transform.TransformData) (interface{}, error) {
+	path := string(d.Param.(relationshipPath))
+	record, ok := d.HydrateItem.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if value, ok := record[path]; ok {
+		return value, nil
+	}
+
+	var value interface{} = record
+	for _, part := range strings.Split(path, ".") {
+		parent, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		value = parent[part]
+	}
+	return value, nil
+}
@@ -0,0 +1,48 @@
+package salesforce
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
+)
+
+func TestRelationshipColumnsArePerConnection(t *testing.T) {
+	t.Setenv("STEAMPIPE_INSTALL_DIR", t.TempDir())
+	f := newFakeSalesforce(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"User","fields":[{"name":"Name","label":"Full Name","type":"string","soapType":"xsd:string","filterable":true}]}`))
+	})
+	objectFields := []map[string]interface{}{
+		{"name": "OwnerId", "type": "reference", "relationshipName": "Owner", "referenceTo": []interface{}{"User"}},
+	}
+	ctx := testContext()
+	apiNative := API_NATIVE
+
+	// The same relationship path becomes a column of each connection, named after its naming convention
+	snakeTd := f.tableMapData("snake", salesforceConfig{})
+	snakeCols, _, snakeSalesforceCols := relationshipColumns(ctx, snakeTd, GetConfig(snakeTd.Connection), objectFields, []string{"Owner.Name"})
+	nativeTd := f.tableMapData("native", salesforceConfig{NamingConvention: &apiNative})
+	nativeCols, _, _ := relationshipColumns(ctx, nativeTd, GetConfig(nativeTd.Connection), objectFields, []string{"Owner.Name"})
+	if len(snakeCols) != 1 || snakeCols[0].Name != "owner__name" || len(nativeCols) != 1 || nativeCols[0].Name != "Owner.Name" {
+		t.Fatalf("relationship columns = %v and %v, want owner__name and Owner.Name", snakeCols, nativeCols)
+	}
+
+	if got := generateQuery(snakeCols, "Opportunity"); got != "SELECT Owner.Name FROM Opportunity" {
+		t.Errorf("generateQuery = %q", got)
+	}
+	qualMap := plugin.KeyColumnQualMap{
+		"owner__name": {Name: "owner__name", Quals: quals.QualSlice{{Column: "owner__name", Operator: "=", Value: stringQualValue("Jane")}}},
+	}
+	if got := buildQueryFromQuals(qualMap, snakeCols, snakeSalesforceCols); got != "Owner.Name = 'Jane'" {
+		t.Errorf("buildQueryFromQuals = %q", got)
+	}
+
+	// Columns of other tables or connections with the same name are not relationship columns
+	if got := getColumnFieldName(&plugin.Column{Name: "owner__name"}); got == "Owner.Name" {
+		t.Errorf("getColumnFieldName of a field column = %q", got)
+	}
+	if got := getColumnFieldName(&plugin.Column{Name: "Owner.Name"}); got == "Owner.Name" {
+		t.Errorf("getColumnFieldName of a field column = %q", got)
+	}
+}
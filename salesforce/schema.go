@@ -0,0 +1,130 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// getColumnNameForField:: returns the column name of a Salesforce field.
+// Don't convert custom fields to snake case since field names can have underscores in
+// them, so it's impossible to convert from snake case back to camel case
+// to match the original field name. Also, if we convert to snake case,
+// custom fields like "TestField" and "Test_Field" will result in duplicates
+func getColumnNameForField(config salesforceConfig, fieldName string) string {
+	// keep the field name as it is if NamingConvention is set to api_native
+	if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+		return fieldName
+	}
+	if strings.HasSuffix(fieldName, "__c") {
+		return strings.ToLower(fieldName)
+	}
+	return strcase.ToSnake(fieldName)
+}
+
+// buildSchemaFromDescribe:: turns the describe payload of an object into columns, key columns and the
+// Salesforce type of each column. If userDefinedDynamicColumns is set, only the listed fields and Id
+// become columns, while the types of all fields are still recorded for qual handling.
+// The describe fields are returned as well to resolve relationship columns.
+func buildSchemaFromDescribe(ctx context.Context, config salesforceConfig, sObjectMeta simpleforce.SObjectMeta, userDefinedDynamicColumns map[string]bool) (dynamicMap, []map[string]interface{}) {
+	schema := dynamicMap{
+		cols:              []*plugin.Column{},
+		keyColumns:        plugin.KeyColumnSlice{},
+		salesforceColumns: map[string]string{},
+		foreignKeys:       []cache.ForeignKeyStruct{},
+		groupableColumns:  map[string]bool{},
+	}
+
+	fields := getDescribeFields(ctx, sObjectMeta)
+	for _, field := range fields {
+		fieldName, ok := field["name"].(string)
+		if !ok || field["soapType"] == nil {
+			continue
+		}
+		// Components of compound fields, e.g. BillingCity, are part of the compound field column
+		if compoundFieldName, ok := field["compoundFieldName"].(string); ok && compoundFieldName != fieldName {
+			continue
+		}
+
+		columnName := getColumnNameForField(config, fieldName)
+		// Adding column type in the map to help in qual handling
+		schema.salesforceColumns[columnName] = getSalesforceFieldType(field)
+
+		// The Id field is always kept, as it is the get key column of the table
+		if len(userDefinedDynamicColumns) != 0 && !userDefinedDynamicColumns[columnName] && fieldName != "Id" {
+			plugin.Logger(ctx).Debug("salesforce.buildSchemaFromDescribe", "ignoring column", columnName)
+			continue
+		}
+
+		label, _ := field["label"].(string)
+		column := plugin.Column{
+			Name:        columnName,
+			Description: fmt.Sprintf("%s.", label),
+			Transform:   transform.FromP(getFieldFromSObjectMap, fieldName),
+		}
+
+		// Set column type based on the `type` from salesforce schema
+		if keyColumn := setColumnTypeFromField(&column, field); keyColumn != nil {
+			schema.keyColumns = append(schema.keyColumns, keyColumn)
+		}
+		schema.cols = append(schema.cols, &column)
+		if groupable, ok := field["groupable"].(bool); ok && groupable {
+			schema.groupableColumns[columnName] = true
+		}
+
+		if foreignKey, ok := getReferenceForeignKey(field); ok {
+			schema.foreignKeys = append(schema.foreignKeys, foreignKey)
+		}
+	}
+	return schema, fields
+}
+
+// getDescribeFields:: returns the fields of a describe payload
+func getDescribeFields(ctx context.Context, sObjectMeta simpleforce.SObjectMeta) []map[string]interface{} {
+	fields := []map[string]interface{}{}
+	fieldsAsByte, err := json.Marshal(sObjectMeta["fields"])
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.getDescribeFields", "json marshal error", err)
+		return fields
+	}
+	err = json.Unmarshal(fieldsAsByte, &fields)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.getDescribeFields", "json unmarshal error", err)
+	}
+	return fields
+}
+
+// describeTableSchema:: describes the object and returns its columns, including the relationship columns
+// configured for it, or nil if the object is not present in Salesforce
+func describeTableSchema(ctx context.Context, td *plugin.TableMapData, config salesforceConfig, objectName string, userDefinedDynamicColumns map[string]bool, relationshipPaths []string, childRelationships []string) *dynamicMap {
+	sObjectMeta := describeSObject(ctx, td, config, objectName)
+	if sObjectMeta == nil {
+		plugin.Logger(ctx).Error("salesforce.describeTableSchema", fmt.Sprintf("Object %s not present in salesforce", objectName))
+		return nil
+	}
+
+	schema, fields := buildSchemaFromDescribe(ctx, config, *sObjectMeta, userDefinedDynamicColumns)
+
+	// Parent relationship columns configured in relationship_column_config
+	relationshipCols, relationshipKeyColumns, relationshipSalesforceCols := relationshipColumns(ctx, td, config, fields, relationshipPaths)
+	schema.cols = append(schema.cols, relationshipCols...)
+	schema.keyColumns = append(schema.keyColumns, relationshipKeyColumns...)
+	for columnName, fieldType := range relationshipSalesforceCols {
+		schema.salesforceColumns[columnName] = fieldType
+	}
+
+	// Child relationship columns configured in child_relationship_config
+	childCols, childSalesforceCols := childRelationshipColumns(ctx, td, config, objectName, *sObjectMeta, childRelationships, schema.salesforceColumns)
+	schema.cols = append(schema.cols, childCols...)
+	for columnName, fieldType := range childSalesforceCols {
+		schema.salesforceColumns[columnName] = fieldType
+	}
+	return &schema
+}
@@ -0,0 +1,159 @@
+package salesforce
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-salesforce/cache"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+)
+
+// readDescribeFixture:: returns a describe payload recorded in testdata/describe
+func readDescribeFixture(t *testing.T, objectName string) simpleforce.SObjectMeta {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "describe", objectName+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := simpleforce.SObjectMeta{}
+	if err = json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestBuildSchemaFromDescribe(t *testing.T) {
+	schema, fields := buildSchemaFromDescribe(testContext(), salesforceConfig{}, readDescribeFixture(t, "Account"), nil)
+	if len(fields) != 13 {
+		t.Errorf("describe fields = %d, want 13", len(fields))
+	}
+
+	columnTypes := map[string]proto.ColumnType{}
+	for _, column := range schema.cols {
+		columnTypes[column.Name] = column.Type
+	}
+	// Compound field components, e.g. BillingCity, are part of the compound field column
+	wantColumnTypes := map[string]proto.ColumnType{
+		"id":                  proto.ColumnType_STRING,
+		"is_deleted":          proto.ColumnType_BOOL,
+		"name":                proto.ColumnType_STRING,
+		"type":                proto.ColumnType_STRING,
+		"billing_address":     proto.ColumnType_JSON,
+		"annual_revenue":      proto.ColumnType_DOUBLE,
+		"number_of_employees": proto.ColumnType_INT,
+		"description":         proto.ColumnType_STRING,
+		"owner_id":            proto.ColumnType_STRING,
+		"created_date":        proto.ColumnType_TIMESTAMP,
+		"interests__c":        proto.ColumnType_JSON,
+		"health_score__c":     proto.ColumnType_DOUBLE,
+	}
+	if !reflect.DeepEqual(columnTypes, wantColumnTypes) {
+		t.Errorf("column types = %v, want %v", columnTypes, wantColumnTypes)
+	}
+
+	keyColumnOperators := map[string][]string{}
+	for _, keyColumn := range schema.keyColumns {
+		keyColumnOperators[keyColumn.Name] = keyColumn.Operators
+	}
+	// Description isn't filterable and compound fields can't be used in conditions
+	wantKeyColumnOperators := map[string][]string{
+		"id":                  {"=", "<>"},
+		"is_deleted":          {"=", "<>"},
+		"name":                {"=", "<>", "~~", "!~~", "~~*", "!~~*"},
+		"type":                {"=", "<>", "~~", "!~~", "~~*", "!~~*"},
+		"annual_revenue":      {"=", "<>", ">", ">=", "<=", "<"},
+		"number_of_employees": {"=", "<>", ">", ">=", "<=", "<"},
+		"owner_id":            {"=", "<>"},
+		"created_date":        {"=", ">", ">=", "<=", "<"},
+		"interests__c":        {"?", "?|", "?&", "@>"},
+		"health_score__c":     {"=", "<>", ">", ">=", "<=", "<"},
+	}
+	if !reflect.DeepEqual(keyColumnOperators, wantKeyColumnOperators) {
+		t.Errorf("key column operators = %v, want %v", keyColumnOperators, wantKeyColumnOperators)
+	}
+
+	if schema.salesforceColumns["owner_id"] != "reference" || schema.salesforceColumns["billing_address"] != "address" || schema.salesforceColumns["created_date"] != "datetime" {
+		t.Errorf("salesforce columns = %v", schema.salesforceColumns)
+	}
+	if want := []cache.ForeignKeyStruct{{Key: "OwnerId", ForeignTableName: "User"}}; !reflect.DeepEqual(schema.foreignKeys, want) {
+		t.Errorf("foreign keys = %v, want %v", schema.foreignKeys, want)
+	}
+	if schema.groupableColumns["name"] != true || schema.groupableColumns["annual_revenue"] {
+		t.Errorf("groupable columns = %v", schema.groupableColumns)
+	}
+}
+
+func TestBuildSchemaFromDescribeUserDefinedColumns(t *testing.T) {
+	userDefinedDynamicColumns := map[string]bool{"name": true, "health_score__c": true}
+	schema, _ := buildSchemaFromDescribe(testContext(), salesforceConfig{}, readDescribeFixture(t, "Account"), userDefinedDynamicColumns)
+
+	// Standard and custom fields are both filtered, while Id is kept for the get key column
+	columnNames := []string{}
+	for _, column := range schema.cols {
+		columnNames = append(columnNames, column.Name)
+	}
+	if want := []string{"id", "name", "health_score__c"}; !reflect.DeepEqual(columnNames, want) {
+		t.Errorf("columns = %v, want %v", columnNames, want)
+	}
+	for _, keyColumn := range schema.keyColumns {
+		if !isColumnAvailable(keyColumn.Name, schema.cols) {
+			t.Errorf("key column %s is not a column", keyColumn.Name)
+		}
+	}
+
+	// Types of the skipped fields are still recorded for qual handling
+	if len(schema.salesforceColumns) != 12 || schema.salesforceColumns["annual_revenue"] != "currency" {
+		t.Errorf("salesforce columns = %v", schema.salesforceColumns)
+	}
+}
+
+func TestBuildSchemaFromDescribeAPINative(t *testing.T) {
+	apiNative := API_NATIVE
+	config := salesforceConfig{NamingConvention: &apiNative}
+	schema, _ := buildSchemaFromDescribe(testContext(), config, readDescribeFixture(t, "Account"), map[string]bool{"Name": true})
+
+	columnNames := []string{}
+	for _, column := range schema.cols {
+		columnNames = append(columnNames, column.Name)
+	}
+	if want := []string{"Id", "Name"}; !reflect.DeepEqual(columnNames, want) {
+		t.Errorf("columns = %v, want %v", columnNames, want)
+	}
+	if checkNameScheme(config, schema.cols) != "Id" {
+		t.Errorf("get key column = %s, want Id", checkNameScheme(config, schema.cols))
+	}
+
+	// Columns read the describe field name from the record
+	column := schema.cols[1]
+	if got := column.Transform.Transforms[0].Param; got != "Name" {
+		t.Errorf("transform param = %v, want Name", got)
+	}
+	if _, ok := getRelationshipColumnPath(column); ok {
+		t.Errorf("column %s is not a relationship column", column.Name)
+	}
+}
+
+func TestStaticTableKeyColumnsSkipNonFilterableFields(t *testing.T) {
+	ctx := testContext()
+	schema, _ := buildSchemaFromDescribe(ctx, salesforceConfig{}, readDescribeFixture(t, "Account"), nil)
+
+	keyColumns := SalesforceAccount(ctx, schema, salesforceConfig{}).List.KeyColumns
+	// Description is a long text area, which Salesforce doesn't accept in conditions
+	if keyColumns.Find("description") != nil {
+		t.Error("description is a key column although the field isn't filterable")
+	}
+	for _, name := range []string{"id", "name", "annual_revenue"} {
+		if keyColumns.Find(name) == nil {
+			t.Errorf("%s is not a key column", name)
+		}
+	}
+
+	// Without a describe schema the columns are filtered based on their type
+	if SalesforceAccount(ctx, dynamicMap{}, salesforceConfig{}).List.KeyColumns.Find("description") == nil {
+		t.Error("description is not a key column of the table built without describe")
+	}
+}
@@ -0,0 +1,109 @@
+package salesforce
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+)
+
+// Escape sequences supported inside SOQL string literals
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_quotedstringescapes.htm
+var soqlStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+)
+
+// escapeSOQLString:: escapes a value so it can be placed between single quotes in a SOQL query
+func escapeSOQLString(value string) string {
+	return soqlStringEscaper.Replace(value)
+}
+
+// soqlStringLiteral:: returns the value as a quoted SOQL string literal, e.g. O'Reilly => 'O\'Reilly'
+func soqlStringLiteral(value string) string {
+	return "'" + escapeSOQLString(value) + "'"
+}
+
+// soqlStringList:: returns the values as a comma separated list of SOQL string literals for IN / NOT IN clauses
+func soqlStringList(values []string) string {
+	literals := make([]string, 0, len(values))
+	for _, value := range values {
+		literals = append(literals, soqlStringLiteral(value))
+	}
+	return strings.Join(literals, ", ")
+}
+
+// soqlBoolLiteral:: returns the SOQL boolean literal for the value
+func soqlBoolLiteral(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// soqlLikePattern:: converts a Postgres LIKE pattern into a quoted SOQL LIKE pattern.
+// '%' and '_' stay wildcards, while Postgres escapes (\%, \_, \\) are kept as literal characters.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_comparisonoperators.htm
+func soqlLikePattern(pattern string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+			switch r {
+			case '%', '_':
+				sb.WriteString(`\` + string(r))
+			default:
+				sb.WriteString(escapeSOQLString(string(r)))
+			}
+		case r == '\\':
+			escaped = true
+		case r == '%' || r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(escapeSOQLString(string(r)))
+		}
+	}
+	// A trailing backslash matches itself in Postgres
+	if escaped {
+		sb.WriteString(`\\`)
+	}
+	return "'" + sb.String() + "'"
+}
+
+// soqlIncludesValues:: returns the INCLUDES argument for a multi-select picklist qual. Values joined by ';'
+// must all be selected, while comma separated values match any of them.
+//   - ? 'a' and ?| array['a', 'b'] => 'a' / 'a', 'b'
+//   - ?& array['a', 'b'] and @> '["a", "b"]' => 'a;b'
+func soqlIncludesValues(operator string, value *proto.QualValue) string {
+	values := []string{}
+	if value.GetListValue() != nil {
+		for _, v := range value.GetListValue().Values {
+			values = append(values, v.GetStringValue())
+		}
+	} else if operator == "@>" {
+		if err := json.Unmarshal([]byte(value.GetJsonbValue()), &values); err != nil {
+			return ""
+		}
+	} else {
+		values = append(values, value.GetStringValue())
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	switch operator {
+	case "?", "?|":
+		return soqlStringList(values)
+	case "?&", "@>":
+		return soqlStringLiteral(strings.Join(values, ";"))
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package salesforce
+
+import "testing"
+
+func TestSOQLStringLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "Acme", `'Acme'`},
+		{"empty", "", `''`},
+		{"single quote", "O'Reilly", `'O\'Reilly'`},
+		{"quote injection", "x' OR Name != '", `'x\' OR Name != \''`},
+		{"double quote", `say "hi"`, `'say \"hi\"'`},
+		{"backslash", `C:\temp`, `'C:\\temp'`},
+		{"escaped quote", `\'`, `'\\\''`},
+		{"newline", "line1\nline2", `'line1\nline2'`},
+		{"carriage return and tab", "a\r\tb", `'a\r\tb'`},
+		{"backspace and form feed", "a\b\fb", `'a\b\fb'`},
+		{"like wildcards are literal", "100%_done", `'100%_done'`},
+		{"unicode", "Zürich 東京 🚀", `'Zürich 東京 🚀'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := soqlStringLiteral(tt.value); got != tt.want {
+				t.Errorf("soqlStringLiteral(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSOQLStringList(t *testing.T) {
+	if got, want := soqlStringList([]string{"a", "O'Reilly"}), `'a', 'O\'Reilly'`; got != want {
+		t.Errorf("soqlStringList = %s, want %s", got, want)
+	}
+}
+
+func TestSOQLLikePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+	}{
+		{"prefix", "Acme%", `'Acme%'`},
+		{"single character wildcard", "A_me", `'A_me'`},
+		{"escaped percent", `100\%`, `'100\%'`},
+		{"escaped underscore", `a\_b%`, `'a\_b%'`},
+		{"escaped backslash", `C:\\temp%`, `'C:\\temp%'`},
+		{"trailing backslash", `abc\`, `'abc\\'`},
+		{"escaped other character", `\a`, `'a'`},
+		{"single quote", "O'Re%", `'O\'Re%'`},
+		{"quote injection", "%' OR Name LIKE '%", `'%\' OR Name LIKE \'%'`},
+		{"double quote", `%"x"%`, `'%\"x\"%'`},
+		{"newline", "a\n%", `'a\n%'`},
+		{"unicode", "Zür%東京_", `'Zür%東京_'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := soqlLikePattern(tt.pattern); got != tt.want {
+				t.Errorf("soqlLikePattern(%q) = %s, want %s", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
@@ -51,7 +51,7 @@ func SalesforceAccount(ctx context.Context, dm dynamicMap, config salesforceConf
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -59,7 +59,7 @@ func SalesforceAccount(ctx context.Context, dm dynamicMap, config salesforceConf
 		Description: "Represents an individual account, which is an organization or person involved with business (such as customers, competitors, and partners).",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
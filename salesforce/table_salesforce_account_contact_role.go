@@ -29,7 +29,7 @@ func SalesforceAccountContactRole(ctx context.Context, dm dynamicMap, config sal
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -37,7 +37,7 @@ func SalesforceAccountContactRole(ctx context.Context, dm dynamicMap, config sal
 		Description: "Represents the role that a Contact plays on an Account.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
@@ -0,0 +1,35 @@
+package salesforce
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func SalesforceActionCreate(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceActionCreate init")
+
+	return &plugin.Table{
+		Name:        "salesforce_action_create",
+		Description: "Creates a record when queried with the object and fields. Requires allow_writes = true in the connection config.",
+		List: &plugin.ListConfig{
+			Hydrate: createSalesforceRecord,
+			Tags:    salesforceTags("write"),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "object", Require: plugin.Required, Operators: []string{"="}},
+				{Name: "fields", Require: plugin.Required, Operators: []string{"="}},
+			},
+		},
+		// Each query must call Salesforce, rather than return the cached result of a previous call
+		Cache: &plugin.TableCacheOptions{Enabled: false},
+		Columns: []*plugin.Column{
+			{Name: "object", Type: proto.ColumnType_STRING, Description: "API name of the Salesforce object, e.g. Account.", Transform: transform.FromQual("object")},
+			{Name: "fields", Type: proto.ColumnType_JSON, Description: "Field values of the new record as a JSON object, e.g. {\"Name\": \"Acme\"}.", Transform: transform.FromQual("fields")},
+			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the new record.", Transform: transform.FromField("ID").NullIfZero()},
+			{Name: "success", Type: proto.ColumnType_BOOL, Description: "True if Salesforce applied the change.", Transform: transform.FromField("Success")},
+			{Name: "errors", Type: proto.ColumnType_JSON, Description: "Errors returned by Salesforce if the change failed.", Transform: transform.FromField("Errors")},
+		},
+	}
+}
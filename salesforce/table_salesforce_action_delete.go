@@ -0,0 +1,34 @@
+package salesforce
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func SalesforceActionDelete(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceActionDelete init")
+
+	return &plugin.Table{
+		Name:        "salesforce_action_delete",
+		Description: "Deletes a record when queried with the object and id. Requires allow_writes = true in the connection config.",
+		List: &plugin.ListConfig{
+			Hydrate: deleteSalesforceRecord,
+			Tags:    salesforceTags("write"),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "object", Require: plugin.Required, Operators: []string{"="}},
+				{Name: "id", Require: plugin.Required, Operators: []string{"="}},
+			},
+		},
+		// Each query must call Salesforce, rather than return the cached result of a previous call
+		Cache: &plugin.TableCacheOptions{Enabled: false},
+		Columns: []*plugin.Column{
+			{Name: "object", Type: proto.ColumnType_STRING, Description: "API name of the Salesforce object, e.g. Account.", Transform: transform.FromQual("object")},
+			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the record to delete.", Transform: transform.FromQual("id")},
+			{Name: "success", Type: proto.ColumnType_BOOL, Description: "True if Salesforce applied the change.", Transform: transform.FromField("Success")},
+			{Name: "errors", Type: proto.ColumnType_JSON, Description: "Errors returned by Salesforce if the change failed.", Transform: transform.FromField("Errors")},
+		},
+	}
+}
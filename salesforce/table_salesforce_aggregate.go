@@ -0,0 +1,220 @@
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// aggregateResult is a single AggregateResult record returned by a SOQL aggregate query
+type aggregateResult struct {
+	Query  string
+	Result map[string]interface{}
+	// values of the filtered fields that are not grouped, keyed by field name
+	Filters map[string]interface{}
+}
+
+// Columns of the aggregate tables besides the object's fields
+var aggregateTableColumns = map[string]bool{"aggregates": true, "group_by": true, "query": true, "result": true}
+
+// getAggregateTableName:: returns the name of the aggregate table of an object table, e.g. salesforce_opportunity_aggregate
+func getAggregateTableName(config salesforceConfig, tableName string) string {
+	if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
+		return tableName + "Aggregate"
+	}
+	return tableName + "_aggregate"
+}
+
+// SalesforceObjectAggregate:: returns the aggregate table of an object, which runs SOQL aggregate queries on it.
+// The table has a column for each groupable field of the object, set for the fields listed in group_by.
+func SalesforceObjectAggregate(ctx context.Context, tableName string, objectName string, dm dynamicMap) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceObjectAggregate init", "table_name", tableName)
+
+	columns := []*plugin.Column{}
+	for _, col := range dm.cols {
+		if !dm.groupableColumns[col.Name] || aggregateTableColumns[col.Name] {
+			continue
+		}
+		column := *col
+		column.Transform = transform.FromP(getAggregateFieldValue, getColumnFieldName(col))
+		columns = append(columns, &column)
+	}
+
+	keyColumns := plugin.KeyColumnSlice{
+		{Name: "aggregates", Require: plugin.Required, Operators: []string{"="}},
+		{Name: "group_by", Require: plugin.Optional, Operators: []string{"="}},
+	}
+	for _, keyColumn := range dm.keyColumns {
+		if isColumnAvailable(keyColumn.Name, columns) {
+			keyColumns = append(keyColumns, keyColumn)
+		}
+	}
+
+	return &plugin.Table{
+		Name:        tableName,
+		Description: fmt.Sprintf("Results of SOQL aggregate queries (COUNT, SUM, AVG, MIN, MAX with optional GROUP BY) on Salesforce object %s.", objectName),
+		List: &plugin.ListConfig{
+			Hydrate:    listSalesforceAggregatesByTable(objectName, dm.salesforceColumns, columns),
+			Tags:       salesforceTags("query"),
+			KeyColumns: keyColumns,
+		},
+		Columns: append(columns, []*plugin.Column{
+			{Name: "aggregates", Type: proto.ColumnType_STRING, Description: "Comma separated aggregate expressions, optionally aliased, e.g. COUNT(Id) total, SUM(Amount) amount.", Transform: transform.FromQual("aggregates")},
+			{Name: "group_by", Type: proto.ColumnType_STRING, Description: "Comma separated columns to group the results by, e.g. stage_name.", Transform: transform.FromQual("group_by")},
+			{Name: "query", Type: proto.ColumnType_STRING, Description: "The SOQL aggregate query sent to Salesforce.", Transform: transform.FromField("Query")},
+			{Name: "result", Type: proto.ColumnType_JSON, Description: "The aggregate result row, keyed by group by field names and aggregate aliases (expr0, expr1, ... if not aliased).", Transform: transform.FromField("Result")},
+		}...),
+	}
+}
+
+//// LIST HYDRATE FUNCTION
+
+func listSalesforceAggregatesByTable(objectName string, salesforceCols map[string]string, columns []*plugin.Column) func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	return func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+		aggregates := strings.TrimSpace(d.EqualsQualString("aggregates"))
+		if aggregates == "" {
+			return nil, nil
+		}
+
+		groupByColumns, err := getAggregateGroupByColumns(d.EqualsQualString("group_by"), columns)
+		if err != nil {
+			return nil, err
+		}
+		filterColumns, filters, err := getAggregateFilterColumns(d.Quals, columns, groupByColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		query := buildAggregateQuery(objectName, aggregates, groupByColumns, buildQueryFromQuals(d.Quals, filterColumns, salesforceCols))
+		plugin.Logger(ctx).Debug("salesforce.listSalesforceAggregatesByTable", "query", query)
+
+		for {
+			result, err := querySalesforce(ctx, d, query)
+			if err != nil {
+				plugin.Logger(ctx).Error("salesforce.listSalesforceAggregatesByTable", "query error", err)
+				return nil, err
+			}
+
+			records := new([]map[string]interface{})
+			err = decodeQueryResult(ctx, result.Records, records)
+			if err != nil {
+				plugin.Logger(ctx).Error("salesforce.listSalesforceAggregatesByTable", "results decoding error", err)
+				return nil, err
+			}
+
+			for _, record := range *records {
+				delete(record, "attributes")
+				d.StreamListItem(ctx, aggregateResult{Query: query, Result: record, Filters: filters})
+
+				// Context may get cancelled due to manual cancellation or if the limit has been reached
+				if d.RowsRemaining(ctx) == 0 {
+					return nil, nil
+				}
+			}
+
+			// Paging
+			if result.Done {
+				break
+			}
+			query = result.NextRecordsURL
+			// Each further page is a separate API call
+			d.WaitForListRateLimit(ctx)
+		}
+
+		return nil, nil
+	}
+}
+
+// getAggregateGroupByColumns:: returns the columns listed in the comma separated group_by qual
+func getAggregateGroupByColumns(groupBy string, columns []*plugin.Column) ([]*plugin.Column, error) {
+	groupByColumns := []*plugin.Column{}
+	for _, name := range strings.Split(groupBy, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		column := getColumn(name, columns)
+		if column == nil {
+			return nil, fmt.Errorf("group_by column %s is not a groupable column", name)
+		}
+		groupByColumns = append(groupByColumns, column)
+	}
+	return groupByColumns, nil
+}
+
+// getAggregateFilterColumns:: returns the columns whose quals become the WHERE clause of the aggregate query.
+// Grouped columns can be filtered with any operator, as the results have their values. Other columns are
+// only set for an equals qual, with the qual value, so filtering them with other operators is an error.
+func getAggregateFilterColumns(keyColumnQuals plugin.KeyColumnQualMap, columns []*plugin.Column, groupByColumns []*plugin.Column) ([]*plugin.Column, map[string]interface{}, error) {
+	filterColumns := []*plugin.Column{}
+	filters := map[string]interface{}{}
+	for _, column := range columns {
+		columnQuals := keyColumnQuals[column.Name]
+		if columnQuals == nil || len(columnQuals.Quals) == 0 {
+			continue
+		}
+		if isColumnAvailable(column.Name, groupByColumns) {
+			filterColumns = append(filterColumns, column)
+			continue
+		}
+		for _, qual := range columnQuals.Quals {
+			if qual.Operator != "=" || qual.Value.GetListValue() != nil || len(columnQuals.Quals) > 1 {
+				return nil, nil, fmt.Errorf("column %s must be listed in group_by to be filtered with %s", column.Name, qual.Operator)
+			}
+			filters[getColumnFieldName(column)] = grpc.GetQualValue(qual.Value)
+		}
+		filterColumns = append(filterColumns, column)
+	}
+	return filterColumns, filters, nil
+}
+
+// buildAggregateQuery:: returns the SOQL aggregate query, e.g.
+// SELECT StageName, COUNT(Id) total FROM Opportunity WHERE IsWon = TRUE GROUP BY StageName
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_agg_functions.htm
+func buildAggregateQuery(objectName string, aggregates string, groupByColumns []*plugin.Column, condition string) string {
+	groupBy := []string{}
+	for _, column := range groupByColumns {
+		groupBy = append(groupBy, getColumnFieldName(column))
+	}
+
+	selectList := aggregates
+	if len(groupBy) > 0 {
+		selectList = fmt.Sprintf("%s, %s", strings.Join(groupBy, ", "), aggregates)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", selectList, objectName)
+	if condition != "" {
+		query = fmt.Sprintf("%s WHERE %s", query, condition)
+	}
+	if len(groupBy) > 0 {
+		query = fmt.Sprintf("%s GROUP BY %s", query, strings.Join(groupBy, ", "))
+	}
+	return query
+}
+
+// getColumn:: returns the column with the name, or nil if there is none
+func getColumn(name string, columns []*plugin.Column) *plugin.Column {
+	for _, column := range columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+//// TRANSFORM FUNCTION
+
+// getAggregateFieldValue:: returns the value of a grouped field, or the qual value of a filtered field
+func getAggregateFieldValue(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	fieldName := d.Param.(string)
+	item := d.HydrateItem.(aggregateResult)
+	if value, ok := item.Result[fieldName]; ok {
+		return value, nil
+	}
+	return item.Filters[fieldName], nil
+}
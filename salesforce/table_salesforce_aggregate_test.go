@@ -0,0 +1,124 @@
+package salesforce
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/simpleforce/simpleforce"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
+)
+
+// Describe fields of an Opportunity, of which Description can't be grouped by
+var opportunityDescribe = simpleforce.SObjectMeta{
+	"name": "Opportunity",
+	"fields": []interface{}{
+		map[string]interface{}{"name": "StageName", "label": "Stage", "type": "picklist", "soapType": "xsd:string", "filterable": true, "groupable": true},
+		map[string]interface{}{"name": "IsWon", "label": "Won", "type": "boolean", "soapType": "xsd:boolean", "filterable": true, "groupable": true},
+		map[string]interface{}{"name": "Amount", "label": "Amount", "type": "currency", "soapType": "xsd:double", "filterable": true, "groupable": false},
+		map[string]interface{}{"name": "Description", "label": "Description", "type": "textarea", "soapType": "xsd:string", "filterable": false, "groupable": false},
+	},
+}
+
+func TestSalesforceObjectAggregate(t *testing.T) {
+	ctx := testContext()
+	schema, _ := buildSchemaFromDescribe(ctx, salesforceConfig{}, opportunityDescribe, nil)
+	table := SalesforceObjectAggregate(ctx, "salesforce_opportunity_aggregate", "Opportunity", schema)
+
+	columnNames := []string{}
+	for _, column := range table.Columns {
+		columnNames = append(columnNames, column.Name)
+	}
+	if want := []string{"stage_name", "is_won", "aggregates", "group_by", "query", "result"}; !reflect.DeepEqual(columnNames, want) {
+		t.Errorf("columns = %v, want %v", columnNames, want)
+	}
+
+	keyColumnNames := []string{}
+	for _, keyColumn := range table.List.KeyColumns {
+		keyColumnNames = append(keyColumnNames, keyColumn.Name)
+	}
+	if want := []string{"aggregates", "group_by", "stage_name", "is_won"}; !reflect.DeepEqual(keyColumnNames, want) {
+		t.Errorf("key columns = %v, want %v", keyColumnNames, want)
+	}
+}
+
+func TestBuildAggregateQuery(t *testing.T) {
+	ctx := testContext()
+	schema, _ := buildSchemaFromDescribe(ctx, salesforceConfig{}, opportunityDescribe, nil)
+	columns := SalesforceObjectAggregate(ctx, "salesforce_opportunity_aggregate", "Opportunity", schema).Columns[:2]
+	isWon := &proto.QualValue{Value: &proto.QualValue_BoolValue{BoolValue: true}}
+
+	tests := []struct {
+		name        string
+		groupBy     string
+		quals       plugin.KeyColumnQualMap
+		want        string
+		wantFilters map[string]interface{}
+		wantErr     bool
+	}{
+		{
+			name:        "no group by",
+			want:        "SELECT COUNT(Id) total FROM Opportunity",
+			wantFilters: map[string]interface{}{},
+		},
+		{
+			name:    "grouped column filter",
+			groupBy: "stage_name",
+			quals: plugin.KeyColumnQualMap{
+				"stage_name": {Name: "stage_name", Quals: quals.QualSlice{{Column: "stage_name", Operator: "~~", Value: stringQualValue("Closed%")}}},
+			},
+			want:        "SELECT StageName, COUNT(Id) total FROM Opportunity WHERE StageName LIKE 'Closed%' GROUP BY StageName",
+			wantFilters: map[string]interface{}{},
+		},
+		{
+			name:    "ungrouped column equals filter",
+			groupBy: "stage_name",
+			quals: plugin.KeyColumnQualMap{
+				"is_won": {Name: "is_won", Quals: quals.QualSlice{{Column: "is_won", Operator: "=", Value: isWon}}},
+			},
+			want:        "SELECT StageName, COUNT(Id) total FROM Opportunity WHERE IsWon = TRUE GROUP BY StageName",
+			wantFilters: map[string]interface{}{"IsWon": true},
+		},
+		{
+			name:    "ungrouped column not equals filter",
+			groupBy: "stage_name",
+			quals: plugin.KeyColumnQualMap{
+				"is_won": {Name: "is_won", Quals: quals.QualSlice{{Column: "is_won", Operator: "<>", Value: isWon}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "group by unknown column",
+			groupBy: "stage_name, amount",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupByColumns, err := getAggregateGroupByColumns(tt.groupBy, columns)
+			var filterColumns []*plugin.Column
+			var filters map[string]interface{}
+			if err == nil {
+				filterColumns, filters, err = getAggregateFilterColumns(tt.quals, columns, groupByColumns)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			query := buildAggregateQuery("Opportunity", "COUNT(Id) total", groupByColumns, buildQueryFromQuals(tt.quals, filterColumns, schema.salesforceColumns))
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+			if !reflect.DeepEqual(filters, tt.wantFilters) {
+				t.Errorf("filters = %v, want %v", filters, tt.wantFilters)
+			}
+		})
+	}
+}
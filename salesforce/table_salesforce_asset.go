@@ -51,7 +51,7 @@ func SalesforceAsset(ctx context.Context, dm dynamicMap, config salesforceConfig
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -59,7 +59,7 @@ func SalesforceAsset(ctx context.Context, dm dynamicMap, config salesforceConfig
 		Description: "Represents an item of commercial value, such as a product sold by your company or a competitor, that a customer has purchased and installed.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
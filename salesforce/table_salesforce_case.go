@@ -65,7 +65,7 @@ func SalesforceCase(ctx context.Context, dm dynamicMap, config salesforceConfig)
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -73,7 +73,7 @@ func SalesforceCase(ctx context.Context, dm dynamicMap, config salesforceConfig)
 		Description: "Represents case records.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
@@ -62,7 +62,7 @@ func SalesforceContact(ctx context.Context, dm dynamicMap, config salesforceConf
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -70,7 +70,7 @@ func SalesforceContact(ctx context.Context, dm dynamicMap, config salesforceConf
 		Description: "Represents a contact, which is a person associated with an account.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
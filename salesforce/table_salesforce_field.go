@@ -17,6 +17,8 @@ func SalesforceField(ctx context.Context, dc map[string]dynamicMap, config sales
 		Description: "A custom field in Salesforce.",
 		List: &plugin.ListConfig{
 			Hydrate: listFields(dc),
+			// Fields are listed from the schemas described when the tables were built, without calling Salesforce
+			Tags: map[string]string{},
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "name", Require: plugin.Optional, Operators: []string{"=", "<>"}},
 				{Name: "type", Require: plugin.Optional, Operators: []string{"=", "<>"}},
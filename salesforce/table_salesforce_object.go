@@ -10,72 +10,25 @@ import (
 
 	"github.com/iancoleman/strcase"
 	"github.com/turbot/steampipe-plugin-salesforce/cache"
-	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-//// LIST HYDRATE FUNCTION
-
-var tableKeyStruct = []cache.KeyStruct{
-	{
-		Name:              "Account",
-		Pk:                "Id",
-		Fk:                []cache.ForeignKeyStruct{},
-		BulkDataPullByIds: bulkDataPullByIds,
-	}, {
-		Name: "Opportunity",
-		Pk:   "Id",
-		Fk: []cache.ForeignKeyStruct{{
-			Key:              "AccountId",
-			ForeignTableName: "Account",
-		}},
-		BulkDataPullByIds: bulkDataPullByIds,
-	}, {
-		Name: "Case",
-		Pk:   "Id",
-		Fk: []cache.ForeignKeyStruct{{
-			Key:              "AccountId",
-			ForeignTableName: "Account",
-		}},
-		BulkDataPullByIds: bulkDataPullByIds,
-	}, {
-		Name: "Order",
-		Pk:   "Id",
-		Fk: []cache.ForeignKeyStruct{{
-			Key:              "AccountId",
-			ForeignTableName: "Account",
-		}},
-		BulkDataPullByIds: bulkDataPullByIds,
-	},
-}
-
+// Record cache settings, the foreign keys it prefetches are built from describe in buildForeignKeyGraph
 var cacheExpiration = 10 * time.Minute
-var cleanupInterval = 10 * time.Minute
 var batchSize = 500
-var idFormatter = func(id string) string {
-	return fmt.Sprintf("'%s'", id)
-}
+var idFormatter = soqlStringLiteral
 
-var cacheUtil = cache.NewCacheUtil(tableKeyStruct, cacheExpiration, cleanupInterval, batchSize, idFormatter)
+//// LIST HYDRATE FUNCTION
 
 func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]string, queryColumnsMap map[string]*plugin.Column) func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	return func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 		startTime := time.Now()
 		defer measureTime(ctx, startTime, "listSalesforceObjectsByTable")
-		client, err := connect(ctx, d)
-		if err != nil {
-			plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "connection error", err)
-			return nil, err
-		}
-		if client == nil {
-			plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "client_not_found: unable to generate dynamic tables because of invalid steampipe salesforce configuration", err)
-			return nil, fmt.Errorf("salesforce.listSalesforceObjectsByTable: client_not_found, unable to query table %s because of invalid steampipe salesforce configuration", d.Table.Name)
-		}
 
 		var queryColumns []*plugin.Column
 		for _, element := range d.QueryContext.Columns {
-			if column, ok := queryColumnsMap[getSalesforceColumnName(element)]; ok {
+			if column, ok := getQueryColumn(queryColumnsMap, element); ok {
 				queryColumns = append(queryColumns, column)
 			} else {
 				plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "no column found", queryColumnsMap, element)
@@ -83,12 +36,12 @@ func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]st
 		}
 
 		query := generateQuery(queryColumns, tableName)
+		salesforceConfig := GetConfig(d.Connection)
 		condition := buildQueryFromQuals(d.Quals, queryColumns, salesforceCols)
 		if condition != "" {
 			query = fmt.Sprintf("%s where %s", query, condition)
 			plugin.Logger(ctx).Debug("salesforce.listSalesforceObjectsByTable", "table_name", d.Table.Name, "query_condition", condition)
 		}
-		salesforceConfig := GetConfig(d.Connection)
 
 		if isColumnAvailable("last_modified_date", d.Table.Columns) {
 			query = fmt.Sprintf("%s  order by lastModifiedDate desc", query)
@@ -106,12 +59,44 @@ func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]st
 			query = fmt.Sprintf("%s  limit %s", query, limitString)
 		}
 
+		// Deleted and archived records are only returned by the queryAll resource
+		queryAll := useQueryAll(d, salesforceConfig)
+		runQuery := querySalesforce
+		if queryAll {
+			runQuery = queryAllSalesforce
+		}
+
+		recordCache := getRecordCache(ctx, d)
 		var totalRecords int = 0
+		if useBulkQuery(ctx, d, tableName, salesforceConfig, queryColumns, salesforceCols, condition) {
+			plugin.Logger(ctx).Debug("salesforce.listSalesforceObjectsByTable", "table_name", d.Table.Name, "query_mode", "bulk")
+			var resultSizeErr error
+			err := listByBulkQuery(ctx, d, query, queryAll, queryColumnsMap, func(record map[string]interface{}) bool {
+				totalRecords++
+				if exceedsResultSize(salesforceConfig, totalRecords) {
+					resultSizeErr = fmt.Errorf("Query returned too many rows, please add a few filters to reduce it.")
+					return false
+				}
+				recordCache.AddIdsToForeignTableCache(ctx, getTableName(tableName), record)
+				d.StreamListItem(ctx, record)
+
+				// Context may get cancelled due to manual cancellation or if the limit has been reached
+				return d.RowsRemaining(ctx) != 0
+			})
+			if err != nil {
+				plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "bulk query error", err)
+				return nil, err
+			}
+			return nil, resultSizeErr
+		}
+
+		childRelationshipNames := getChildRelationshipNames(queryColumns)
+
 		var dataList [][]map[string]interface{}
 		for {
 			plugin.Logger(ctx).Debug("salesforce.listSalesforceObjectsByTable getting results for query : ", query)
 
-			result, err := client.Query(query)
+			result, err := runQuery(ctx, d, query)
 			if err != nil {
 				plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "query error", err)
 				return nil, err
@@ -124,8 +109,15 @@ func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]st
 				return nil, err
 			}
 
+			for _, record := range *AccountList {
+				if err = fetchChildRelationshipRecords(ctx, d, record, childRelationshipNames); err != nil {
+					plugin.Logger(ctx).Error("salesforce.listSalesforceObjectsByTable", "child relationship query error", err)
+					return nil, err
+				}
+			}
+
 			totalRecords += len(*AccountList)
-			if *salesforceConfig.ShowResultSizeError && totalRecords > *salesforceConfig.ResultSize {
+			if exceedsResultSize(salesforceConfig, totalRecords) {
 				return nil, fmt.Errorf("Query returned too many rows, please add a few filters to reduce it.")
 			}
 
@@ -137,17 +129,12 @@ func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]st
 			} else {
 				query = result.NextRecordsURL
 			}
+			// Each further page is a separate API call
+			d.WaitForListRateLimit(ctx)
 		}
 		for _, data := range dataList {
 			for _, account := range data {
-				for col_name, col_value := range account {
-					if column_obj, ok := queryColumnsMap[col_name]; ok {
-						if column_obj.Type == proto.ColumnType_STRING && col_value != nil && !strings.HasSuffix(col_name, "id") && !strings.HasSuffix(col_name, "Id") {
-							account[col_name] = strings.ToLower(col_value.(string))
-						}
-					}
-				}
-				cacheUtil.AddIdsToForeignTableCache(ctx, getTableName(tableName), account)
+				recordCache.AddIdsToForeignTableCache(ctx, getTableName(tableName), account)
 			}
 
 			for _, account := range data {
@@ -159,69 +146,62 @@ func listSalesforceObjectsByTable(tableName string, salesforceCols map[string]st
 	}
 }
 
-func bulkDataPullByIds(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData, ids []string, columnsMap map[string]*plugin.Column) (*[]map[string]interface{}, error) {
-	startTime := time.Now()
-	defer measureTime(ctx, startTime, "bulkDataPullByIds")
-
-	var queryColumns []*plugin.Column
-	for _, element := range columnsMap {
-		queryColumns = append(queryColumns, element)
-	}
-	// make query call to get data and update cache
-	// make query call to get data
-	query := generateQuery(queryColumns, getTableName(d.Table.Name))
+// bulkDataPullByIds:: returns the function the record cache uses to fetch records of the object in batches of ids
+func bulkDataPullByIds(tableName string) cache.BulkDataPullByIdsFunc {
+	return func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData, ids []string, columnsMap map[string]*plugin.Column) (*[]map[string]interface{}, error) {
+		startTime := time.Now()
+		defer measureTime(ctx, startTime, "bulkDataPullByIds")
 
-	// Concatenate the values into a comma-separated string
-	inClause := strings.Join(ids, ",")
+		var queryColumns []*plugin.Column
+		for _, element := range columnsMap {
+			queryColumns = append(queryColumns, element)
+		}
+		// make query call to get data and update cache
+		// make query call to get data
+		query := generateQuery(queryColumns, tableName)
+		childRelationshipNames := getChildRelationshipNames(queryColumns)
 
-	// Create the WHERE clause
-	whereClause := fmt.Sprintf("WHERE Id IN (%s)", inClause)
-	query = fmt.Sprintf("%s  %s", query, whereClause)
+		// Concatenate the values into a comma-separated string
+		inClause := strings.Join(ids, ",")
 
-	client, err := connect(ctx, d)
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "connection error", err)
-		return nil, err
-	}
-	if client == nil {
-		plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "client_not_found: unable to generate dynamic tables because of invalid steampipe salesforce configuration", err)
-		return nil, fmt.Errorf("salesforce.bulkDataPullByIds: client_not_found, unable to query table %s because of invalid steampipe salesforce configuration", d.Table.Name)
-	}
+		// Create the WHERE clause
+		whereClause := fmt.Sprintf("WHERE Id IN (%s)", inClause)
+		query = fmt.Sprintf("%s  %s", query, whereClause)
 
-	plugin.Logger(ctx).Debug("salesforce.bulkDataPullByIds GET getting results for query : ", query)
+		plugin.Logger(ctx).Debug("salesforce.bulkDataPullByIds GET getting results for query : ", query)
 
-	data := new([]map[string]interface{})
-	for {
-		result, err := client.Query(query)
-		if err != nil {
-			plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "query error", err)
-			return nil, err
-		}
-		temp := new([]map[string]interface{})
-		err = decodeQueryResult(ctx, result.Records, temp)
-		if err != nil {
-			plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "results decoding error", err)
-			return nil, err
-		}
-		for _, data := range *temp {
-			for col_name, col_value := range data {
-				if column_obj, ok := columnsMap[col_name]; ok {
-					if column_obj.Type == proto.ColumnType_STRING && col_value != nil && !strings.HasSuffix(col_name, "id") && !strings.HasSuffix(col_name, "Id") {
-						data[col_name] = strings.ToLower(col_value.(string))
-					}
+		data := new([]map[string]interface{})
+		for {
+			// Batches are prefetched within a Get call, so each query waits for its rate limiter
+			d.WaitForListRateLimit(ctx)
+			result, err := querySalesforce(ctx, d, query)
+			if err != nil {
+				plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "query error", err)
+				return nil, err
+			}
+			temp := new([]map[string]interface{})
+			err = decodeQueryResult(ctx, result.Records, temp)
+			if err != nil {
+				plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "results decoding error", err)
+				return nil, err
+			}
+			for _, record := range *temp {
+				if err = fetchChildRelationshipRecords(ctx, d, record, childRelationshipNames); err != nil {
+					plugin.Logger(ctx).Error("salesforce.bulkDataPullByIds", "child relationship query error", err)
+					return nil, err
 				}
 			}
-		}
-		// Paging
-		if result.Done {
-			*data = append(*data, *temp...)
-			break
-		} else {
-			query = result.NextRecordsURL
-		}
+			// Paging
+			if result.Done {
+				*data = append(*data, *temp...)
+				break
+			} else {
+				query = result.NextRecordsURL
+			}
 
+		}
+		return data, nil
 	}
-	return data, nil
 }
 
 func getSalesforceObjectbyID(tableName string, queryColumnsMap map[string]*plugin.Column) func(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
@@ -242,12 +222,12 @@ func getSalesforceObjectbyID(tableName string, queryColumnsMap map[string]*plugi
 
 		columnsMap := make(map[string]*plugin.Column)
 		for _, name := range d.QueryContext.Columns {
-			if column, ok := queryColumnsMap[getSalesforceColumnName(name)]; ok {
-				columnsMap[getSalesforceColumnName(name)] = column
+			if column, ok := getQueryColumn(queryColumnsMap, name); ok {
+				columnsMap[getColumnFieldName(column)] = column
 			}
 		}
 
-		record, err := cacheUtil.GetRecordByIdAndBuildCache(ctx, d, h, getTableName(tableName), id, columnsMap)
+		record, err := getRecordCache(ctx, d).GetRecordByIdAndBuildCache(ctx, d, h, getTableName(tableName), id, columnsMap)
 		if record != nil {
 			return record, nil
 		}
@@ -255,32 +235,62 @@ func getSalesforceObjectbyID(tableName string, queryColumnsMap map[string]*plugi
 			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "error getting record from cache", err)
 		}
 
-		client, err := connect(ctx, d)
+		// Not found errors are ignored through the plugin's DefaultGetConfig, other errors fail the query
+		object, err := getSObject(ctx, d, tableName, id)
 		if err != nil {
-			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "connection error", err)
+			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "table_name", tableName, "id", id, "error", err)
 			return nil, err
 		}
-		if client == nil {
-			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "client_not_found: unable to generate dynamic tables because of invalid steampipe salesforce configuration", err)
-			return nil, fmt.Errorf("salesforce.getSalesforceObjectbyID: client_not_found, unable to query table %s because of invalid steampipe salesforce configuration", d.Table.Name)
+
+		// The sObject rows resource doesn't return child records
+		columns := make([]*plugin.Column, 0, len(columnsMap))
+		for _, column := range columnsMap {
+			columns = append(columns, column)
+		}
+		if err = addChildRelationshipRecords(ctx, d, tableName, id, columns, object); err != nil {
+			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "child relationship query error", err)
+			return nil, err
 		}
 
-		obj := client.SObject(tableName).Get(id)
-		if obj == nil {
-			// Object doesn't exist, handle the error
-			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", fmt.Sprintf("%s with id \"%s\" not found", tableName, id))
-			return nil, nil
+		return object, nil
+	}
+}
+
+// getQueryColumn:: returns the column with the name from the table's columns, which are keyed by Salesforce field
+func getQueryColumn(queryColumnsMap map[string]*plugin.Column, name string) (*plugin.Column, bool) {
+	if column, ok := queryColumnsMap[getSalesforceColumnName(name)]; ok && column.Name == name {
+		return column, true
+	}
+	// Relationship columns are keyed by their relationship path
+	for _, column := range queryColumnsMap {
+		if column.Name == name {
+			return column, true
 		}
+	}
+	return nil, false
+}
 
-		object := new(map[string]interface{})
-		err = decodeQueryResult(ctx, obj, object)
-		if err != nil {
-			plugin.Logger(ctx).Error("salesforce.getSalesforceObjectbyID", "result decoding error", err)
-			return nil, err
+// useQueryAll:: checks if the list query should include deleted and archived records, which is the case
+// if include_deleted is set or if the query filters on the IsDeleted or IsArchived fields
+func useQueryAll(d *plugin.QueryData, config salesforceConfig) bool {
+	if config.IncludeDeleted != nil && *config.IncludeDeleted {
+		return true
+	}
+	for columnName := range d.Quals {
+		switch getSalesforceColumnName(columnName) {
+		case "IsDeleted", "IsArchived":
+			return true
 		}
+	}
+	return false
+}
 
-		return *object, nil
+// exceedsResultSize:: checks if the number of fetched records is above the configured result_size
+func exceedsResultSize(config salesforceConfig, totalRecords int) bool {
+	if config.ShowResultSizeError == nil || !*config.ShowResultSizeError || config.ResultSize == nil {
+		return false
 	}
+	return totalRecords > *config.ResultSize
 }
 
 //// TRANSFORM FUNCTION
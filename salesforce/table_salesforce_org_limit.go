@@ -0,0 +1,107 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// orgLimit is a single limit returned by the limits resource
+type orgLimit struct {
+	Name        string
+	Max         int64
+	Remaining   int64
+	Used        int64
+	PercentUsed float64
+}
+
+func SalesforceOrgLimit(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceOrgLimit init")
+
+	return &plugin.Table{
+		Name:        "salesforce_org_limit",
+		Description: "Maximum and remaining allocation of each limit of the organization, such as DailyApiRequests.",
+		List: &plugin.ListConfig{
+			Hydrate: listSalesforceOrgLimits,
+			Tags:    salesforceTags("limits"),
+		},
+		Columns: []*plugin.Column{
+			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the limit, e.g. DailyApiRequests.", Transform: transform.FromField("Name")},
+			{Name: "max", Type: proto.ColumnType_INT, Description: "Maximum allocation of the limit.", Transform: transform.FromField("Max")},
+			{Name: "remaining", Type: proto.ColumnType_INT, Description: "Remaining allocation of the limit.", Transform: transform.FromField("Remaining")},
+			{Name: "used", Type: proto.ColumnType_INT, Description: "Used allocation of the limit.", Transform: transform.FromField("Used")},
+			{Name: "percent_used", Type: proto.ColumnType_DOUBLE, Description: "Percentage of the maximum allocation that is used.", Transform: transform.FromField("PercentUsed")},
+		},
+	}
+}
+
+func listSalesforceOrgLimits(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	limits, err := getOrgLimits(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, limit := range limits {
+		d.StreamListItem(ctx, limit)
+
+		// Context may get cancelled due to manual cancellation or if the limit has been reached
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// getOrgLimits:: returns the limits of the organization. The limits are how the API usage is checked, so they
+// are fetched even if min_api_remaining_percent is reached, and the response updates the usage the budget
+// is checked against.
+func getOrgLimits(ctx context.Context, d *plugin.QueryData) ([]orgLimit, error) {
+	path := fmt.Sprintf("services/data/v%s/limits", getAPIVersion(GetConfig(d.Connection)))
+	_, data, _, err := connectionRequest(ctx, d.ConnectionCache, d.Connection, http.MethodGet, path, nil, nil)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.getOrgLimits", "request error", err)
+		return nil, err
+	}
+
+	limits, err := decodeOrgLimits(data)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.getOrgLimits", "results decoding error", err)
+		return nil, err
+	}
+	return limits, nil
+}
+
+// decodeOrgLimits:: converts the limits resource response, e.g. {"DailyApiRequests": {"Max": 15000, "Remaining": 14998}},
+// into limits sorted by name. Per connected app usage nested in some limits is ignored.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_limits.htm
+func decodeOrgLimits(data []byte) ([]orgLimit, error) {
+	response := map[string]struct {
+		Max       int64 `json:"Max"`
+		Remaining int64 `json:"Remaining"`
+	}{}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	limits := make([]orgLimit, 0, len(response))
+	for name, value := range response {
+		limit := orgLimit{
+			Name:      name,
+			Max:       value.Max,
+			Remaining: value.Remaining,
+			Used:      value.Max - value.Remaining,
+		}
+		if value.Max > 0 {
+			limit.PercentUsed = float64(limit.Used) * 100 / float64(value.Max)
+		}
+		limits = append(limits, limit)
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i].Name < limits[j].Name })
+	return limits, nil
+}
@@ -36,7 +36,7 @@ func SalesforcePermissionSet(ctx context.Context, dm dynamicMap, config salesfor
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -44,7 +44,7 @@ func SalesforcePermissionSet(ctx context.Context, dm dynamicMap, config salesfor
 		Description: "Represents a set of permissions that's used to grant more access to one or more users without changing their profile or reassigning profiles.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
@@ -22,7 +22,7 @@ func SalesforcePermissionSetAssignment(ctx context.Context, dm dynamicMap, confi
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -30,7 +30,7 @@ func SalesforcePermissionSetAssignment(ctx context.Context, dm dynamicMap, confi
 		Description: "Represents the association between a User and a PermissionSet.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
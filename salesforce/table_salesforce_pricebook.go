@@ -34,7 +34,7 @@ func SalesforcePricebook(ctx context.Context, dm dynamicMap, config salesforceCo
 
 	queryColumnsMap := make(map[string]*plugin.Column)
 	for _, column := range columns {
-		queryColumnsMap[getSalesforceColumnName(column.Name)] = column
+		queryColumnsMap[getColumnFieldName(column)] = column
 	}
 
 	return &plugin.Table{
@@ -42,7 +42,7 @@ func SalesforcePricebook(ctx context.Context, dm dynamicMap, config salesforceCo
 		Description: "Represents a price book that contains the list of products that your org sells.",
 		List: &plugin.ListConfig{
 			Hydrate:    listSalesforceObjectsByTable(tableName, dm.salesforceColumns, queryColumnsMap),
-			KeyColumns: getKeyColumns(columns),
+			KeyColumns: getKeyColumns(columns, dm),
 		},
 		Get: &plugin.GetConfig{
 			Hydrate:    getSalesforceObjectbyID(tableName, queryColumnsMap),
@@ -0,0 +1,90 @@
+package salesforce
+
+import (
+	"context"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// queryRecord is a single record returned by a raw SOQL query
+type queryRecord struct {
+	ID             string
+	AttributesType string
+	Record         map[string]interface{}
+}
+
+func SalesforceQuery(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceQuery init")
+
+	return &plugin.Table{
+		Name:        "salesforce_query",
+		Description: "Records returned by a SOQL query, for SOQL features generated tables can't express such as relationship queries, semi-joins and date literals.",
+		List: &plugin.ListConfig{
+			Hydrate: listSalesforceQueryRecords,
+			Tags:    salesforceTags("query"),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "query", Require: plugin.Required, Operators: []string{"="}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{Name: "query", Type: proto.ColumnType_STRING, Description: "The SOQL query to run, e.g. SELECT Id, Name, Account.Name FROM Contact WHERE CreatedDate = LAST_N_DAYS:30.", Transform: transform.FromQual("query")},
+			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the record, if selected by the query.", Transform: transform.FromField("ID").NullIfZero()},
+			{Name: "attributes_type", Type: proto.ColumnType_STRING, Description: "Salesforce object type of the record, from the record attributes.", Transform: transform.FromField("AttributesType").NullIfZero()},
+			{Name: "record", Type: proto.ColumnType_JSON, Description: "The record as returned by Salesforce, including nested parent and child relationship results.", Transform: transform.FromField("Record")},
+		},
+	}
+}
+
+func listSalesforceQueryRecords(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	query := strings.TrimSpace(d.EqualsQualString("query"))
+	if query == "" {
+		return nil, nil
+	}
+	plugin.Logger(ctx).Debug("salesforce.listSalesforceQueryRecords", "query", query)
+
+	for {
+		result, err := querySalesforce(ctx, d, query)
+		if err != nil {
+			plugin.Logger(ctx).Error("salesforce.listSalesforceQueryRecords", "query error", err)
+			return nil, err
+		}
+
+		records := new([]map[string]interface{})
+		err = decodeQueryResult(ctx, result.Records, records)
+		if err != nil {
+			plugin.Logger(ctx).Error("salesforce.listSalesforceQueryRecords", "results decoding error", err)
+			return nil, err
+		}
+
+		for _, record := range *records {
+			row := queryRecord{Record: record}
+			if id, ok := record["Id"].(string); ok {
+				row.ID = id
+			}
+			if attributes, ok := record["attributes"].(map[string]interface{}); ok {
+				if attributesType, ok := attributes["type"].(string); ok {
+					row.AttributesType = attributesType
+				}
+			}
+			d.StreamListItem(ctx, row)
+
+			// Context may get cancelled due to manual cancellation or if the limit has been reached
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+
+		// Paging
+		if result.Done {
+			break
+		}
+		query = result.NextRecordsURL
+		// Each further page is a separate API call
+		d.WaitForListRateLimit(ctx)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,78 @@
+package salesforce
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// recordCacheStats are the metrics of the cached records of an object, with the shared memory budget
+type recordCacheStats struct {
+	ObjectName        string
+	Records           int
+	SizeBytes         int64
+	MaxRecords        int
+	PendingIds        int
+	Hits              int64
+	Misses            int64
+	Evictions         int64
+	MemoryBudgetBytes int64
+	MemoryUsedBytes   int64
+}
+
+func SalesforceRecordCache(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceRecordCache init")
+
+	return &plugin.Table{
+		Name:        "salesforce_record_cache",
+		Description: "Metrics of the plugin's record cache for each object of the connection, for diagnosing memory use and prefetching.",
+		List: &plugin.ListConfig{
+			Hydrate: listSalesforceRecordCacheStats,
+			Tags:    salesforceTags("cache"),
+		},
+		// Metrics change with every query, so results are never served from the query cache
+		Cache: &plugin.TableCacheOptions{
+			Enabled: false,
+		},
+		Columns: []*plugin.Column{
+			{Name: "object_name", Type: proto.ColumnType_STRING, Description: "API name of the cached object, e.g. Account.", Transform: transform.FromField("ObjectName")},
+			{Name: "records", Type: proto.ColumnType_INT, Description: "Number of records of the object in the cache.", Transform: transform.FromField("Records")},
+			{Name: "size_bytes", Type: proto.ColumnType_INT, Description: "Estimated memory used by the cached records of the object.", Transform: transform.FromField("SizeBytes")},
+			{Name: "max_records", Type: proto.ColumnType_INT, Description: "Maximum number of cached records of the object, 0 if unbounded.", Transform: transform.FromField("MaxRecords")},
+			{Name: "pending_ids", Type: proto.ColumnType_INT, Description: "Number of referenced record ids waiting to be prefetched.", Transform: transform.FromField("PendingIds")},
+			{Name: "hits", Type: proto.ColumnType_INT, Description: "Number of record lookups served from the cache.", Transform: transform.FromField("Hits")},
+			{Name: "misses", Type: proto.ColumnType_INT, Description: "Number of record lookups that had to query Salesforce.", Transform: transform.FromField("Misses")},
+			{Name: "evictions", Type: proto.ColumnType_INT, Description: "Number of records evicted to stay within max_records or the memory budget.", Transform: transform.FromField("Evictions")},
+			{Name: "memory_budget_bytes", Type: proto.ColumnType_INT, Description: "Memory budget shared by the record caches of all connections, 0 if unbounded.", Transform: transform.FromField("MemoryBudgetBytes")},
+			{Name: "memory_used_bytes", Type: proto.ColumnType_INT, Description: "Estimated memory used by the record caches of all connections.", Transform: transform.FromField("MemoryUsedBytes")},
+		},
+	}
+}
+
+func listSalesforceRecordCacheStats(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	recordCache := getRecordCache(ctx, d)
+	budgetBytes, usedBytes := recordCacheBudget.MaxBytes(), recordCacheBudget.UsedBytes()
+
+	for _, stats := range recordCache.Stats() {
+		d.StreamListItem(ctx, recordCacheStats{
+			ObjectName:        stats.Name,
+			Records:           stats.Records,
+			SizeBytes:         stats.Bytes,
+			MaxRecords:        stats.MaxRecordsPerTable,
+			PendingIds:        stats.PendingIds,
+			Hits:              stats.Hits,
+			Misses:            stats.Misses,
+			Evictions:         stats.Evictions,
+			MemoryBudgetBytes: budgetBytes,
+			MemoryUsedBytes:   usedBytes,
+		})
+
+		// Context may get cancelled due to manual cancellation or if the limit has been reached
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,167 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Reserved characters of a SOSL FIND {...} clause, which are escaped so the search term is searched as is
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_sosl_find.htm
+var soslSearchTermEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`?`, `\?`,
+	`&`, `\&`,
+	`|`, `\|`,
+	`!`, `\!`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`(`, `\(`,
+	`)`, `\)`,
+	`^`, `\^`,
+	`~`, `\~`,
+	`*`, `\*`,
+	`:`, `\:`,
+	`"`, `\"`,
+	`'`, `\'`,
+	`+`, `\+`,
+	`-`, `\-`,
+)
+
+// searchRecord is a single record returned by a SOSL search
+type searchRecord struct {
+	ObjectType string
+	ID         string
+	Record     map[string]interface{}
+}
+
+func SalesforceSearch(ctx context.Context, config salesforceConfig) *plugin.Table {
+	plugin.Logger(ctx).Debug("SalesforceSearch init")
+
+	return &plugin.Table{
+		Name:        "salesforce_search",
+		Description: "Records matching a SOSL full-text search across Salesforce objects.",
+		List: &plugin.ListConfig{
+			Hydrate: listSalesforceSearchRecords,
+			Tags:    salesforceTags("search"),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "search_term", Require: plugin.Required, Operators: []string{"="}},
+				{Name: "objects", Require: plugin.Optional, Operators: []string{"="}},
+				{Name: "in_fields", Require: plugin.Optional, Operators: []string{"="}},
+				{Name: "limit", Require: plugin.Optional, Operators: []string{"="}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{Name: "search_term", Type: proto.ColumnType_STRING, Description: "The text to search for. Reserved characters, including the wildcards * and ?, are searched as is, while the operators AND, OR and AND NOT are supported.", Transform: transform.FromQual("search_term")},
+			{Name: "objects", Type: proto.ColumnType_STRING, Description: "Objects and fields to return, used as the SOSL RETURNING clause, e.g. Account(Id, Name), Contact(Id, Name, Email). By default only Ids of all searchable objects are returned.", Transform: transform.FromQual("objects")},
+			{Name: "in_fields", Type: proto.ColumnType_STRING, Description: "Fields to search in: ALL (default), NAME, EMAIL, PHONE or SIDEBAR.", Transform: transform.FromQual("in_fields")},
+			{Name: "limit", Type: proto.ColumnType_INT, Description: "Maximum number of records to return, used as the SOSL LIMIT clause.", Transform: transform.FromQual("limit")},
+			{Name: "object_type", Type: proto.ColumnType_STRING, Description: "Salesforce object type of the matching record.", Transform: transform.FromField("ObjectType")},
+			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the matching record.", Transform: transform.FromField("ID").NullIfZero()},
+			{Name: "record", Type: proto.ColumnType_JSON, Description: "Fields of the matching record requested in objects.", Transform: transform.FromField("Record")},
+		},
+	}
+}
+
+func listSalesforceSearchRecords(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	searchTerm := strings.TrimSpace(d.EqualsQualString("search_term"))
+	if searchTerm == "" {
+		return nil, nil
+	}
+
+	inFields := strings.ToUpper(strings.TrimSpace(d.EqualsQualString("in_fields")))
+	switch inFields {
+	case "":
+		inFields = "ALL"
+	case "ALL", "NAME", "EMAIL", "PHONE", "SIDEBAR":
+	default:
+		return nil, fmt.Errorf("invalid in_fields value %q, supported values are ALL, NAME, EMAIL, PHONE and SIDEBAR", inFields)
+	}
+
+	var limit int64
+	if d.EqualsQuals["limit"] != nil {
+		limit = d.EqualsQuals["limit"].GetInt64Value()
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid limit value %d, the limit must be greater than 0", limit)
+		}
+	}
+	if d.QueryContext.Limit != nil && (limit == 0 || *d.QueryContext.Limit < limit) {
+		limit = *d.QueryContext.Limit
+	}
+	search := buildSearchQuery(searchTerm, inFields, strings.TrimSpace(d.EqualsQualString("objects")), limit)
+	plugin.Logger(ctx).Debug("salesforce.listSalesforceSearchRecords", "search", search)
+
+	path := fmt.Sprintf("services/data/v%s/search?q=%s", getAPIVersion(GetConfig(d.Connection)), url.QueryEscape(search))
+	data, _, err := restRequest(ctx, d, http.MethodGet, path, nil, "")
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.listSalesforceSearchRecords", "search error", err)
+		return nil, err
+	}
+
+	records, err := decodeSearchResult(data)
+	if err != nil {
+		plugin.Logger(ctx).Error("salesforce.listSalesforceSearchRecords", "results decoding error", err)
+		return nil, err
+	}
+
+	for _, record := range records {
+		row := searchRecord{Record: record}
+		if id, ok := record["Id"].(string); ok {
+			row.ID = id
+		}
+		if attributes, ok := record["attributes"].(map[string]interface{}); ok {
+			if objectType, ok := attributes["type"].(string); ok {
+				row.ObjectType = objectType
+			}
+		}
+		delete(record, "attributes")
+		d.StreamListItem(ctx, row)
+
+		// Context may get cancelled due to manual cancellation or if the limit has been reached
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// buildSearchQuery:: returns the SOSL search, e.g.
+// FIND {Acme*} IN NAME FIELDS RETURNING Account(Id, Name), Contact(Id, Name) LIMIT 20
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_sosl_syntax.htm
+func buildSearchQuery(searchTerm string, inFields string, objects string, limit int64) string {
+	search := fmt.Sprintf("FIND {%s} IN %s FIELDS", soslSearchTermEscaper.Replace(searchTerm), inFields)
+	if objects != "" {
+		search = fmt.Sprintf("%s RETURNING %s", search, objects)
+	}
+	if limit > 0 {
+		search = fmt.Sprintf("%s LIMIT %d", search, limit)
+	}
+	return search
+}
+
+// decodeSearchResult:: returns the records of a search response. API versions before 37.0
+// return the records as a bare array instead of a searchRecords property.
+func decodeSearchResult(data []byte) ([]map[string]interface{}, error) {
+	var result struct {
+		SearchRecords []map[string]interface{} `json:"searchRecords"`
+	}
+	if err := json.Unmarshal(data, &result); err == nil {
+		return result.SearchRecords, nil
+	}
+
+	records := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
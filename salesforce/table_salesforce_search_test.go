@@ -0,0 +1,30 @@
+package salesforce
+
+import "testing"
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		searchTerm string
+		inFields   string
+		objects    string
+		limit      int64
+		want       string
+	}{
+		{"term", "Acme", "ALL", "", 0, `FIND {Acme} IN ALL FIELDS`},
+		{"returning and limit", "Acme", "NAME", "Account(Id, Name)", 20, `FIND {Acme} IN NAME FIELDS RETURNING Account(Id, Name) LIMIT 20`},
+		{"operators", "Acme AND NOT Globex", "ALL", "", 0, `FIND {Acme AND NOT Globex} IN ALL FIELDS`},
+		{"wildcards", "Acme* Corp?", "ALL", "", 0, `FIND {Acme\* Corp\?} IN ALL FIELDS`},
+		{"braces", "} IN ALL FIELDS RETURNING User(Id) {", "ALL", "", 0, `FIND {\} IN ALL FIELDS RETURNING User\(Id\) \{} IN ALL FIELDS`},
+		{"quotes and backslash", `O'Reilly "C:\temp"`, "ALL", "", 0, `FIND {O\'Reilly \"C\:\\temp\"} IN ALL FIELDS`},
+		{"reserved characters", `a&b|c!d[e]f^g~h+i-j`, "ALL", "", 0, `FIND {a\&b\|c\!d\[e\]f\^g\~h\+i\-j} IN ALL FIELDS`},
+		{"email", "jane-doe@example.com", "EMAIL", "", 0, `FIND {jane\-doe@example.com} IN EMAIL FIELDS`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildSearchQuery(tt.searchTerm, tt.inFields, tt.objects, tt.limit); got != tt.want {
+				t.Errorf("buildSearchQuery = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
@@ -13,7 +14,6 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/connection"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
-	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -25,62 +25,22 @@ func connect(ctx context.Context, d *plugin.QueryData) (*simpleforce.Client, err
 // connect:: returns salesforce client after authentication
 func connectRaw(ctx context.Context, cc *connection.ConnectionCache, c *plugin.Connection) (*simpleforce.Client, error) {
 	// Load connection from cache, which preserves throttling protection etc
-	cacheKey := "simpleforce"
 	if cc != nil {
-		if cachedData, ok := cc.Get(ctx, cacheKey); ok {
+		if cachedData, ok := cc.Get(ctx, clientCacheKey); ok {
 			return cachedData.(*simpleforce.Client), nil
 		}
 	}
 
 	config := GetConfig(c)
-	apiVersion := simpleforce.DefaultAPIVersion
-	clientID := "steampipe"
-	securityToken := ""
-
-	if config.ClientId != nil {
-		clientID = *config.ClientId
-	}
-
-	if config.APIVersion != nil {
-		apiVersion = *config.APIVersion
-	}
-
-	if config.Username == nil {
-		plugin.Logger(ctx).Warn("salesforce.connectRaw", "'username' must be set in the connection configuration. Edit your connection configuration file and then restart Steampipe")
-		return nil, nil
-	}
-
-	if config.Password == nil {
-		plugin.Logger(ctx).Warn("salesforce.connectRaw", "'password' must be set in the connection configuration. Edit your connection configuration file and then restart Steampipe")
-		return nil, nil
-	}
-
-	// The Salesforce security token is only required If the client's IP address is not added to the organization's list of trusted IPs
-	// https://help.salesforce.com/s/articleView?id=sf.security_networkaccess.htm&type=5
-	// https://migration.trujay.com/help/how-to-add-an-ip-address-to-whitelist-on-salesforce/
-	if config.Token != nil {
-		securityToken = *config.Token
-	}
-
-	// setup client
-	client := simpleforce.NewClient(*config.URL, clientID, apiVersion)
-	if client == nil {
-		plugin.Logger(ctx).Error("salesforce.connectRaw", "couldn't get salesforce client. Client setup error.")
-		return nil, fmt.Errorf("salesforce.connectRaw couldn't get salesforce client. Client setup error.")
-	}
-
-	// LoginPassword signs into salesforce using password. token is optional if trusted IP is configured.
-	// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/intro_understanding_username_password_oauth_flow.htm
-	// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api.meta/api/sforce_api_calls_login.htm
-	err := client.LoginPassword(*config.Username, *config.Password, securityToken)
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.connectRaw", "client login error", err)
-		return nil, fmt.Errorf("client login error %v", err)
+	client, err := login(ctx, config)
+	if err != nil || client == nil {
+		return nil, err
 	}
+	trackAPIUsage(c.Name, client)
 
 	// Save to cache
 	if cc != nil {
-		err = cc.Set(ctx, cacheKey, client)
+		err = cc.Set(ctx, clientCacheKey, client)
 		if err != nil {
 			plugin.Logger(ctx).Error("connectRaw", "cache-set", err)
 		}
@@ -93,7 +53,12 @@ func connectRaw(ctx context.Context, cc *connection.ConnectionCache, c *plugin.C
 func generateQuery(columns []*plugin.Column, tableName string) string {
 	var queryColumns []string
 	for _, column := range columns {
-		queryColumns = append(queryColumns, getSalesforceColumnName(column.Name))
+		// Child relationship columns are fetched through a subquery, e.g. (SELECT Id, Name FROM Contacts)
+		if relationship, ok := getChildRelationship(column); ok {
+			queryColumns = append(queryColumns, relationship.Subquery)
+			continue
+		}
+		queryColumns = append(queryColumns, getColumnFieldName(column))
 	}
 
 	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(queryColumns, ", "), tableName)
@@ -119,6 +84,9 @@ func decodeQueryResult(ctx context.Context, response interface{}, respObject int
 // buildQueryFromQuals :: generate api_native based on the contions specified in sql query
 // refrences
 // - https://developer.salesforce.com/docs/atlas.en-us.234.0.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_comparisonoperators.htm
+// Salesforce compares text case-insensitively, so = and LIKE return a superset that Postgres rechecks, while
+// <> and NOT IN would also drop values only differing in case, which Postgres can't restore. These are only
+// pushed down for id and reference fields, which are compared exactly.
 func buildQueryFromQuals(equalQuals plugin.KeyColumnQualMap, tableColumns []*plugin.Column, salesforceCols map[string]string) string {
 	filters := []string{}
 
@@ -149,58 +117,82 @@ func buildQueryFromQuals(equalQuals plugin.KeyColumnQualMap, tableColumns []*plu
 									stringValueSlice = append(stringValueSlice, q.GetStringValue())
 								}
 								if len(stringValueSlice) > 0 {
-									filters = append(filters, fmt.Sprintf("%s IN ('%s')", getSalesforceColumnName(filterQualItem.Name), strings.Join(stringValueSlice, "','")))
+									filters = append(filters, fmt.Sprintf("%s IN (%s)", getColumnFieldName(filterQualItem), soqlStringList(stringValueSlice)))
 								}
 							case "<>":
+								if !isIDFieldType(salesforceCols[filterQual.Name]) {
+									continue
+								}
 								stringValueSlice := []string{}
 								for _, q := range value.GetListValue().Values {
 									stringValueSlice = append(stringValueSlice, q.GetStringValue())
 								}
 								if len(stringValueSlice) > 0 {
-									filters = append(filters, fmt.Sprintf("%s NOT IN ('%s')", getSalesforceColumnName(filterQualItem.Name), strings.Join(stringValueSlice, "','")))
+									filters = append(filters, fmt.Sprintf("%s NOT IN (%s)", getColumnFieldName(filterQualItem), soqlStringList(stringValueSlice)))
 								}
 							}
 						} else {
 							switch qual.Operator {
 							case "=":
-								filters = append(filters, fmt.Sprintf("%s = '%s'", getSalesforceColumnName(filterQualItem.Name), value.GetStringValue()))
+								filters = append(filters, fmt.Sprintf("%s = %s", getColumnFieldName(filterQualItem), soqlStringLiteral(value.GetStringValue())))
 							case "<>":
-								filters = append(filters, fmt.Sprintf("%s != '%s'", getSalesforceColumnName(filterQualItem.Name), value.GetStringValue()))
+								if isIDFieldType(salesforceCols[filterQual.Name]) {
+									filters = append(filters, fmt.Sprintf("%s != %s", getColumnFieldName(filterQualItem), soqlStringLiteral(value.GetStringValue())))
+								}
+							// SOQL LIKE is always case-insensitive, so both LIKE and ILIKE can be pushed down as
+							// Postgres rechecks the returned rows. NOT ILIKE (!~~*) matches SOQL NOT LIKE exactly,
+							// while NOT LIKE (!~~) is case-sensitive in Postgres and is left to Postgres.
+							// LIKE is not supported on ID fields.
+							case "~~", "~~*":
+								if !isIDFieldType(salesforceCols[filterQual.Name]) {
+									filters = append(filters, fmt.Sprintf("%s LIKE %s", getColumnFieldName(filterQualItem), soqlLikePattern(value.GetStringValue())))
+								}
+							case "!~~*":
+								if !isIDFieldType(salesforceCols[filterQual.Name]) {
+									filters = append(filters, fmt.Sprintf("(NOT %s LIKE %s)", getColumnFieldName(filterQualItem), soqlLikePattern(value.GetStringValue())))
+								}
 							}
 						}
 					case proto.ColumnType_BOOL:
 						switch qual.Operator {
 						case "<>":
-							filters = append(filters, fmt.Sprintf("%s = %s", getSalesforceColumnName(filterQualItem.Name), "FALSE"))
+							filters = append(filters, fmt.Sprintf("%s = %s", getColumnFieldName(filterQualItem), soqlBoolLiteral(!value.GetBoolValue())))
 						case "=":
-							filters = append(filters, fmt.Sprintf("%s = %s", getSalesforceColumnName(filterQualItem.Name), "TRUE"))
+							filters = append(filters, fmt.Sprintf("%s = %s", getColumnFieldName(filterQualItem), soqlBoolLiteral(value.GetBoolValue())))
 						}
 					case proto.ColumnType_INT:
 						switch qual.Operator {
 						case "<>":
-							filters = append(filters, fmt.Sprintf("%s != %d", getSalesforceColumnName(filterQualItem.Name), value.GetInt64Value()))
+							filters = append(filters, fmt.Sprintf("%s != %d", getColumnFieldName(filterQualItem), value.GetInt64Value()))
 						default:
-							filters = append(filters, fmt.Sprintf("%s %s %d", getSalesforceColumnName(filterQualItem.Name), qual.Operator, value.GetInt64Value()))
+							filters = append(filters, fmt.Sprintf("%s %s %d", getColumnFieldName(filterQualItem), qual.Operator, value.GetInt64Value()))
 						}
 					case proto.ColumnType_DOUBLE:
 						switch qual.Operator {
 						case "<>":
-							filters = append(filters, fmt.Sprintf("%s != %f", getSalesforceColumnName(filterQualItem.Name), value.GetDoubleValue()))
+							filters = append(filters, fmt.Sprintf("%s != %s", getColumnFieldName(filterQualItem), strconv.FormatFloat(value.GetDoubleValue(), 'f', -1, 64)))
 						default:
-							filters = append(filters, fmt.Sprintf("%s %s %f", getSalesforceColumnName(filterQualItem.Name), qual.Operator, value.GetDoubleValue()))
+							filters = append(filters, fmt.Sprintf("%s %s %s", getColumnFieldName(filterQualItem), qual.Operator, strconv.FormatFloat(value.GetDoubleValue(), 'f', -1, 64)))
+						}
+					case proto.ColumnType_JSON:
+						// Multi-select picklists, e.g. colors ? 'Red' => Colors__c INCLUDES ('Red')
+						// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_querying_multiselect_picklists.htm
+						if salesforceCols[filterQual.Name] == "multipicklist" {
+							if includes := soqlIncludesValues(qual.Operator, value); includes != "" {
+								filters = append(filters, fmt.Sprintf("%s INCLUDES (%s)", getColumnFieldName(filterQualItem), includes))
+							}
 						}
-					// Need a way to distinguish b/w date and dateTime fields
 					case proto.ColumnType_TIMESTAMP:
 						// https://developer.salesforce.com/docs/atlas.en-us.234.0.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_dateformats.htm
 						if salesforceCols[filterQual.Name] == "date" {
 							switch qual.Operator {
 							case "=", ">=", ">", "<=", "<":
-								filters = append(filters, fmt.Sprintf("%s %s %s", getSalesforceColumnName(filterQualItem.Name), qual.Operator, value.GetTimestampValue().AsTime().Format("2006-01-02")))
+								filters = append(filters, fmt.Sprintf("%s %s %s", getColumnFieldName(filterQualItem), qual.Operator, value.GetTimestampValue().AsTime().Format("2006-01-02")))
 							}
 						} else {
 							switch qual.Operator {
 							case "=", ">=", ">", "<=", "<":
-								filters = append(filters, fmt.Sprintf("%s %s %s", getSalesforceColumnName(filterQualItem.Name), qual.Operator, value.GetTimestampValue().AsTime().Format("2006-01-02T15:04:05Z")))
+								filters = append(filters, fmt.Sprintf("%s %s %s", getColumnFieldName(filterQualItem), qual.Operator, value.GetTimestampValue().AsTime().Format("2006-01-02T15:04:05Z")))
 							}
 						}
 					}
@@ -217,6 +209,19 @@ func buildQueryFromQuals(equalQuals plugin.KeyColumnQualMap, tableColumns []*plu
 	return ""
 }
 
+// getColumnFieldName:: returns the Salesforce field of a column, the relationship path of a relationship column or
+// the relationship name of a child relationship column, which is the key of the column's value in a record
+func getColumnFieldName(column *plugin.Column) string {
+	if path, ok := getRelationshipColumnPath(column); ok {
+		return path
+	}
+	// The relationship name of custom relationships, e.g. Custom_Children__r, can't be derived from the column name
+	if relationship, ok := getChildRelationship(column); ok {
+		return relationship.RelationshipName
+	}
+	return getSalesforceColumnName(column.Name)
+}
+
 func getSalesforceColumnName(name string) string {
 	var columnName string
 	// Salesforce custom fields are suffixed with '__c' and are not converted to
@@ -267,103 +272,6 @@ func mergeTableColumns(_ context.Context, config salesforceConfig, dynamicColumn
 	return columns
 }
 
-// dynamicColumns:: Returns list coulms for a salesforce object
-func dynamicColumns(ctx context.Context, client *simpleforce.Client, salesforceTableName string, config salesforceConfig, userDefinedDynamicColumns map[string]bool) ([]*plugin.Column, plugin.KeyColumnSlice, map[string]string) {
-	sObjectMeta := client.SObject(salesforceTableName).Describe()
-	if sObjectMeta == nil {
-		plugin.Logger(ctx).Error("salesforce.dynamicColumns", fmt.Sprintf("Table %s not present in salesforce", salesforceTableName))
-		return []*plugin.Column{}, plugin.KeyColumnSlice{}, map[string]string{}
-	}
-
-	// Top columns
-	cols := []*plugin.Column{}
-	salesforceCols := map[string]string{}
-	// Key columns
-	keyColumns := plugin.KeyColumnSlice{}
-
-	salesforceObjectMetadata := *sObjectMeta
-	salesforceObjectMetadataAsByte, err := json.Marshal(salesforceObjectMetadata["fields"])
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.dynamicColumns", "json marshal error %v", err)
-	}
-
-	salesforceObjectFields := []map[string]interface{}{}
-	// var queryColumns []string
-	err = json.Unmarshal(salesforceObjectMetadataAsByte, &salesforceObjectFields)
-	if err != nil {
-		plugin.Logger(ctx).Error("salesforce.dynamicColumns", "json unmarshal error %v", err)
-	}
-	for _, fields := range salesforceObjectFields {
-		if fields["name"] == nil {
-			continue
-		}
-		fieldName := fields["name"].(string)
-		compoundFieldName := fields["compoundFieldName"]
-		if compoundFieldName != nil && compoundFieldName.(string) != fieldName {
-			continue
-		}
-
-		if fields["soapType"] == nil {
-			continue
-		}
-		soapType := strings.Split((fields["soapType"]).(string), ":")
-		fieldType := soapType[len(soapType)-1]
-
-		// Column dynamic generation
-		// Don't convert to snake case since field names can have underscores in
-		// them, so it's impossible to convert from snake case back to camel case
-		// to match the original field name. Also, if we convert to snake case,
-		// custom fields like "TestField" and "Test_Field" will result in duplicates
-		// check if DynamicTableAndPropertyNames is true
-		var columnFieldName string
-
-		// keep the field name as it is if NamingConvention is set to api_native
-		if config.NamingConvention != nil && *config.NamingConvention == "api_native" {
-			columnFieldName = fieldName
-		} else if strings.HasSuffix(fieldName, "__c") {
-			columnFieldName = strings.ToLower(fieldName)
-		} else {
-			columnFieldName = strcase.ToSnake(fieldName)
-		}
-
-		salesforceCols[columnFieldName] = fieldType
-
-		if len(userDefinedDynamicColumns) != 0 && userDefinedDynamicColumns[columnFieldName] != true {
-			plugin.Logger(ctx).Info("salesforce.dynamicColumns", fmt.Sprintf("Ignoring column %s ", columnFieldName))
-			continue
-		}
-
-		column := plugin.Column{
-			Name:        columnFieldName,
-			Description: fmt.Sprintf("%s.", fields["label"].(string)),
-			Transform:   transform.FromP(getFieldFromSObjectMap, fieldName),
-		}
-
-		// Set column type based on the `soapType` from salesforce schema
-		switch fieldType {
-		case "string", "ID":
-			column.Type = proto.ColumnType_STRING
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", "<>"}})
-		case "date", "dateTime":
-			column.Type = proto.ColumnType_TIMESTAMP
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		case "boolean":
-			column.Type = proto.ColumnType_BOOL
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", "<>"}})
-		case "double":
-			column.Type = proto.ColumnType_DOUBLE
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		case "int":
-			column.Type = proto.ColumnType_INT
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: columnFieldName, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
-		default:
-			column.Type = proto.ColumnType_JSON
-		}
-		cols = append(cols, &column)
-	}
-	return cols, keyColumns, salesforceCols
-}
-
 // isColumnAvailable:: Checks if the column is not present in the existing columns slice
 func isColumnAvailable(columnName string, columns []*plugin.Column) bool {
 	for _, col := range columns {
@@ -374,12 +282,17 @@ func isColumnAvailable(columnName string, columns []*plugin.Column) bool {
 	return false
 }
 
-func getKeyColumns(columns []*plugin.Column) plugin.KeyColumnSlice {
+// getKeyColumns:: returns the key columns of a static table. Columns of described fields that aren't key
+// columns of the describe schema, e.g. long text areas that aren't filterable, are left to Postgres to filter.
+func getKeyColumns(columns []*plugin.Column, dm dynamicMap) plugin.KeyColumnSlice {
 	keyColumns := plugin.KeyColumnSlice{}
 	for _, col := range columns {
+		if _, described := dm.salesforceColumns[col.Name]; described && dm.keyColumns.Find(col.Name) == nil {
+			continue
+		}
 		switch col.Type {
 		case proto.ColumnType_STRING:
-			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: col.Name, Require: plugin.Optional, Operators: []string{"=", "<>"}})
+			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: col.Name, Require: plugin.Optional, Operators: []string{"=", "<>", "~~", "!~~", "~~*", "!~~*"}})
 		case proto.ColumnType_TIMESTAMP:
 			keyColumns = append(keyColumns, &plugin.KeyColumn{Name: col.Name, Require: plugin.Optional, Operators: []string{"=", ">", ">=", "<=", "<"}})
 		case proto.ColumnType_BOOL:
@@ -423,6 +336,10 @@ func getCustomCols(dm dynamicMap) []*plugin.Column {
 	for _, col := range dm.cols {
 		if _, ok := customFieldMap[col.Name]; ok {
 			customCols = append(customCols, col)
+		} else if _, ok := getRelationshipColumnPath(col); ok {
+			customCols = append(customCols, col)
+		} else if dm.salesforceColumns[col.Name] == childRelationshipFieldType {
+			customCols = append(customCols, col)
 		}
 	}
 	return customCols
@@ -0,0 +1,66 @@
+package salesforce
+
+import (
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
+)
+
+func stringQualValue(value string) *proto.QualValue {
+	return &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: value}}
+}
+
+func stringListQualValue(values ...string) *proto.QualValue {
+	list := &proto.QualValueList{}
+	for _, value := range values {
+		list.Values = append(list.Values, stringQualValue(value))
+	}
+	return &proto.QualValue{Value: &proto.QualValue_ListValue{ListValue: list}}
+}
+
+func TestBuildQueryFromQuals(t *testing.T) {
+	columns := []*plugin.Column{
+		{Name: "name", Type: proto.ColumnType_STRING},
+		{Name: "id", Type: proto.ColumnType_STRING},
+		{Name: "is_deleted", Type: proto.ColumnType_BOOL},
+		{Name: "number_of_employees", Type: proto.ColumnType_INT},
+	}
+	salesforceCols := map[string]string{"name": "string", "id": "id", "is_deleted": "boolean", "number_of_employees": "int"}
+
+	tests := []struct {
+		name     string
+		column   string
+		operator string
+		value    *proto.QualValue
+		want     string
+	}{
+		{"equal", "name", "=", stringQualValue("O'Reilly"), `Name = 'O\'Reilly'`},
+		// Salesforce would also drop the rows only differing in case, which Postgres can't restore
+		{"not equal", "name", "<>", stringQualValue("Acme"), ""},
+		{"in", "name", "=", stringListQualValue("Acme", "Globex"), `Name IN ('Acme', 'Globex')`},
+		{"not in", "name", "<>", stringListQualValue("Acme", "Globex"), ""},
+		{"not equal id", "id", "<>", stringQualValue("001000000000001AAA"), `Id != '001000000000001AAA'`},
+		{"not in id", "id", "<>", stringListQualValue("001000000000001AAA", "001000000000002AAA"), `Id NOT IN ('001000000000001AAA', '001000000000002AAA')`},
+		{"like", "name", "~~", stringQualValue("Acme%"), `Name LIKE 'Acme%'`},
+		{"ilike", "name", "~~*", stringQualValue("acme%"), `Name LIKE 'acme%'`},
+		{"not ilike", "name", "!~~*", stringQualValue("acme%"), `(NOT Name LIKE 'acme%')`},
+		// NOT LIKE is case-sensitive in Postgres, Salesforce would drop rows only differing in case
+		{"not like", "name", "!~~", stringQualValue("Acme%"), ""},
+		// LIKE is not supported on ID fields
+		{"like id", "id", "~~", stringQualValue("001%"), ""},
+		{"bool", "is_deleted", "<>", &proto.QualValue{Value: &proto.QualValue_BoolValue{BoolValue: true}}, "IsDeleted = FALSE"},
+		{"int", "number_of_employees", ">", &proto.QualValue{Value: &proto.QualValue_Int64Value{Int64Value: 100}}, "NumberOfEmployees > 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qualMap := plugin.KeyColumnQualMap{
+				tt.column: {Name: tt.column, Quals: quals.QualSlice{{Column: tt.column, Operator: tt.operator, Value: tt.value}}},
+			}
+			if got := buildQueryFromQuals(qualMap, columns, salesforceCols); got != tt.want {
+				t.Errorf("buildQueryFromQuals = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}